@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func fakeCmd(args []string) error {
+	fs := flag.NewFlagSet("fake", flag.ExitOnError)
+	pkgDir := fs.String("pkg", ".", "directory of the package that declares the interface")
+	typeName := fs.String("type", "", "the interface to fake, such as Clock")
+	outPath := fs.String("out", "", "file to write the fake to, instead of stdout")
+	fs.Parse(args)
+	if *typeName == "" {
+		return fmt.Errorf("-type is required")
+	}
+	src, err := fakeSource(*pkgDir, *typeName)
+	if err != nil {
+		return err
+	}
+	if *outPath == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*outPath, src, 0o644)
+}
+
+// fakeSource returns the source of a fake of the interface typeName, declared
+// in the package in dir, that embeds a fuzzing.Fake.
+func fakeSource(dir string, typeName string) ([]byte, error) {
+	pkgName, err := packageName(dir)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, iface, err := findInterface(fset, dir, pkgName, typeName)
+	if err != nil {
+		return nil, err
+	}
+	fakeName := "fake" + strings.ToUpper(typeName[:1]) + typeName[1:]
+	body := &bytes.Buffer{}
+	usedPkgs := map[string]bool{}
+	for _, method := range iface.Methods.List {
+		funcType, ok := method.Type.(*ast.FuncType)
+		if !ok {
+			return nil, fmt.Errorf("%s embeds %s, list its methods instead", typeName, nodeString(fset, method.Type))
+		}
+		ast.Inspect(funcType, func(n ast.Node) bool {
+			if selector, ok := n.(*ast.SelectorExpr); ok {
+				if ident, ok := selector.X.(*ast.Ident); ok {
+					usedPkgs[ident.Name] = true
+				}
+			}
+			return true
+		})
+		for _, name := range method.Names {
+			if name.Name == "Fake" {
+				return nil, fmt.Errorf("%s has a method Fake, which clashes with the embedded fuzzing.Fake", typeName)
+			}
+			writeFakeMethod(body, fset, fakeName, name.Name, funcType)
+		}
+	}
+
+	src := &bytes.Buffer{}
+	fmt.Fprintf(src, "// Code generated by fuzz-all fake. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkgName)
+	fmt.Fprintf(src, "\t%q\n", "github.com/hugoklepsch/go-fuzz-all/fuzzing")
+	for _, imp := range usedImports(file, usedPkgs) {
+		fmt.Fprintf(src, "\t%s\n", imp)
+	}
+	fmt.Fprintf(src, ")\n\n")
+	fmt.Fprintf(src, "// %s is a fake %s whose results are drawn from the fuzz input.\n", fakeName, typeName)
+	fmt.Fprintf(src, "type %s struct {\n\t*fuzzing.Fake\n}\n\n", fakeName)
+	fmt.Fprintf(src, "// %sGen generates fakes of %s, for fuzzing.WithGen.\n", fakeName, typeName)
+	fmt.Fprintf(src, "var %sGen = fuzzing.FakeGen(func(f *fuzzing.Fake) %s { return %s{f} })\n", fakeName, typeName, fakeName)
+	src.Write(body.Bytes())
+	return format.Source(src.Bytes())
+}
+
+// findInterface returns the declaration of the interface typeName in the
+// package pkgName in dir, and the file that declares it.
+func findInterface(fset *token.FileSet, dir string, pkgName string, typeName string) (*ast.File, *ast.InterfaceType, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(matches)
+	for _, match := range matches {
+		file, err := parser.ParseFile(fset, match, nil, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		if file.Name.Name != pkgName {
+			continue
+		}
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if typeSpec.Name.Name != typeName {
+					continue
+				}
+				if typeSpec.TypeParams != nil {
+					return nil, nil, fmt.Errorf("%s is generic, which is not supported", typeName)
+				}
+				iface, ok := typeSpec.Type.(*ast.InterfaceType)
+				if !ok {
+					return nil, nil, fmt.Errorf("%s is not an interface", typeName)
+				}
+				return file, iface, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("no type %s in %s", typeName, dir)
+}
+
+// writeFakeMethod writes the method name of the fake, which records the call
+// and draws the results. The receiver, the results and unnamed parameters get
+// names that do not clash with the named parameters.
+func writeFakeMethod(w *bytes.Buffer, fset *token.FileSet, fakeName string, name string, funcType *ast.FuncType) {
+	// any is used in the body, so a parameter named any is renamed.
+	used := map[string]bool{"any": true}
+	for _, field := range funcType.Params.List {
+		for _, paramName := range field.Names {
+			used[paramName.Name] = true
+		}
+	}
+	params := []string{}
+	args := []string{}
+	for _, field := range funcType.Params.List {
+		typeString := nodeString(fset, field.Type)
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent("")}
+		}
+		for _, paramName := range names {
+			arg := paramName.Name
+			if arg == "" || arg == "_" || arg == "any" {
+				arg = freshName(fmt.Sprintf("a%d", len(args)), used)
+			}
+			params = append(params, arg+" "+typeString)
+			args = append(args, arg)
+		}
+	}
+	results := []string{}
+	resultPtrs := []string{}
+	if funcType.Results != nil {
+		for _, field := range funcType.Results.List {
+			typeString := nodeString(fset, field.Type)
+			count := len(field.Names)
+			if count == 0 {
+				count = 1
+			}
+			for i := 0; i < count; i++ {
+				result := freshName(fmt.Sprintf("r%d", len(results)), used)
+				results = append(results, result+" "+typeString)
+				resultPtrs = append(resultPtrs, "&"+result)
+			}
+		}
+	}
+	receiver := freshName("f", used)
+	fmt.Fprintf(w, "\nfunc (%s %s) %s(%s)", receiver, fakeName, name, strings.Join(params, ", "))
+	if len(results) > 0 {
+		fmt.Fprintf(w, " (%s)", strings.Join(results, ", "))
+	}
+	// Call through the embedded Fake, as the interface may have a Call method.
+	callArgs := append([]string{strconv.Quote(name), "[]any{" + strings.Join(args, ", ") + "}"}, resultPtrs...)
+	fmt.Fprintf(w, " {\n\t%s.Fake.Call(%s)\n", receiver, strings.Join(callArgs, ", "))
+	if len(results) > 0 {
+		fmt.Fprintf(w, "\treturn\n")
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+// freshName returns name, with underscores appended until it is not in used,
+// and adds it to used.
+func freshName(name string, used map[string]bool) string {
+	for used[name] {
+		name += "_"
+	}
+	used[name] = true
+	return name
+}
+
+// usedImports returns the import specs of file for the packages named in
+// used, as Go source.
+func usedImports(file *ast.File, used map[string]bool) []string {
+	imports := []string{}
+	for _, imp := range file.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if !used[name] {
+			continue
+		}
+		if imp.Name != nil {
+			imports = append(imports, imp.Name.Name+" "+imp.Path.Value)
+		} else {
+			imports = append(imports, imp.Path.Value)
+		}
+	}
+	return imports
+}
+
+func nodeString(fset *token.FileSet, node ast.Node) string {
+	b := &bytes.Buffer{}
+	printer.Fprint(b, fset, node)
+	return b.String()
+}
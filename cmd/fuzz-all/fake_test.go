@@ -0,0 +1,128 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+const fakeTestPackage = `package store
+
+import (
+	"context"
+	"io"
+	tm "time"
+)
+
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(_ context.Context, key, val string) error
+	Touch(tm.Duration, ...string)
+	Close()
+}
+
+type Closer interface {
+	io.Closer
+}
+
+type NotAnInterface struct{}
+
+type Caller interface {
+	Call(f func(r0 int) error, any string) (n int, err error)
+	Calls() []string
+}
+
+type Faker interface {
+	Fake() bool
+}
+`
+
+func TestFakeSource(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "store.go"), []byte(fakeTestPackage), 0o644))
+	src, err := fakeSource(dir, "Store")
+	require.NoError(t, err)
+	expected := `// Code generated by fuzz-all fake. DO NOT EDIT.
+
+package store
+
+import (
+	"context"
+	"github.com/hugoklepsch/go-fuzz-all/fuzzing"
+	tm "time"
+)
+
+// fakeStore is a fake Store whose results are drawn from the fuzz input.
+type fakeStore struct {
+	*fuzzing.Fake
+}
+
+// fakeStoreGen generates fakes of Store, for fuzzing.WithGen.
+var fakeStoreGen = fuzzing.FakeGen(func(f *fuzzing.Fake) Store { return fakeStore{f} })
+
+func (f fakeStore) Get(ctx context.Context, key string) (r0 []byte, r1 error) {
+	f.Fake.Call("Get", []any{ctx, key}, &r0, &r1)
+	return
+}
+
+func (f fakeStore) Put(a0 context.Context, key string, val string) (r0 error) {
+	f.Fake.Call("Put", []any{a0, key, val}, &r0)
+	return
+}
+
+func (f fakeStore) Touch(a0 tm.Duration, a1 ...string) {
+	f.Fake.Call("Touch", []any{a0, a1})
+}
+
+func (f fakeStore) Close() {
+	f.Fake.Call("Close", []any{})
+}
+`
+	assert.Equal(t, expected, string(src))
+}
+
+func TestFakeSource_Clashes(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "store.go"), []byte(fakeTestPackage), 0o644))
+	src, err := fakeSource(dir, "Caller")
+	require.NoError(t, err)
+	assert.Contains(t, string(src), `
+func (f_ fakeCaller) Call(f func(r0 int) error, a1 string) (r0 int, r1 error) {
+	f_.Fake.Call("Call", []any{f, a1}, &r0, &r1)
+	return
+}
+
+func (f fakeCaller) Calls() (r0 []string) {
+	f.Fake.Call("Calls", []any{}, &r0)
+	return
+}
+`)
+
+	// The fake compiles, with the Call and Calls methods of Caller shadowing
+	// those of the embedded fuzzing.Fake.
+	module, err := filepath.Abs(filepath.Join("..", ".."))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module store\n\ngo 1.23\n\nrequire github.com/hugoklepsch/go-fuzz-all v0.0.0\n\nreplace github.com/hugoklepsch/go-fuzz-all => "+module+"\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fake_caller.go"), src, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "check.go"), []byte("package store\n\nvar _ Caller = fakeCaller{}\n"), 0o644))
+	cmd := exec.Command("go", "vet", "-mod=mod", ".")
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "%s", output)
+}
+
+func TestFakeSource_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "store.go"), []byte(fakeTestPackage), 0o644))
+	_, err := fakeSource(dir, "Closer")
+	assert.EqualError(t, err, "Closer embeds io.Closer, list its methods instead")
+	_, err = fakeSource(dir, "Faker")
+	assert.EqualError(t, err, "Faker has a method Fake, which clashes with the embedded fuzzing.Fake")
+	_, err = fakeSource(dir, "NotAnInterface")
+	assert.EqualError(t, err, "NotAnInterface is not an interface")
+	_, err = fakeSource(dir, "Missing")
+	assert.EqualError(t, err, "no type Missing in "+dir)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hugoklepsch/go-fuzz-all/fuzzing"
+)
+
+// helperFileName is the test file generated in the package of the fuzzed type.
+const helperFileName = "fuzz_all_helper_test.go"
+
+// helperTestName is the test that runs fuzzing.ToolHelper.
+const helperTestName = "TestFuzzAllHelper"
+
+// runHelper executes request for the type and options of tf, declared in the
+// package in tf.pkgDir, and returns what the helper wrote.
+func runHelper(tf typeFlags, request fuzzing.ToolRequest) ([]byte, error) {
+	pkgDir := tf.pkgDir
+	pkgName, err := packageName(pkgDir)
+	if err != nil {
+		return nil, err
+	}
+	helperPath := filepath.Join(pkgDir, helperFileName)
+	if _, err := os.Stat(helperPath); err == nil {
+		return nil, fmt.Errorf("%s already exists, remove it and try again", helperPath)
+	}
+	if err := os.WriteFile(helperPath, []byte(helperSource(pkgName, tf.typeExpr, tf.optsExpr)), 0o644); err != nil {
+		return nil, err
+	}
+	defer os.Remove(helperPath)
+
+	tmpDir, err := os.MkdirTemp("", "fuzz-all")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	request.Output = filepath.Join(tmpDir, "output")
+	requestData, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	requestPath := filepath.Join(tmpDir, "request.json")
+	if err := os.WriteFile(requestPath, requestData, 0o644); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("go", "test", "-count=1", "-run", "^"+helperTestName+"$", ".")
+	cmd.Dir = pkgDir
+	cmd.Env = append(os.Environ(), fuzzing.ToolRequestEnv+"="+requestPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s: %w\n%s", strings.Join(cmd.Args, " "), err, output)
+	}
+	return os.ReadFile(request.Output)
+}
+
+func helperSource(pkgName string, typeExpr string, optsExpr string) string {
+	opts := ""
+	if optsExpr != "" {
+		opts = ", " + optsExpr + "..."
+	}
+	return fmt.Sprintf(`// Code generated by fuzz-all. DO NOT EDIT.
+
+package %s
+
+import (
+	"testing"
+
+	"github.com/hugoklepsch/go-fuzz-all/fuzzing"
+)
+
+func %s(t *testing.T) {
+	fuzzing.ToolHelper[%s](t%s)
+}
+`, pkgName, helperTestName, typeExpr, opts)
+}
+
+// packageName returns the name of the package in dir. Test files of an
+// external test package are ignored, as the helper must be able to refer to
+// unexported types.
+func packageName(dir string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(matches)
+	fs := token.NewFileSet()
+	for _, match := range matches {
+		file, err := parser.ParseFile(fs, match, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", err
+		}
+		name := file.Name.Name
+		if strings.HasSuffix(match, "_test.go") && strings.HasSuffix(name, "_test") {
+			continue
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("no Go package in %s", dir)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackageName(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a_test.go"), []byte("package foo_test\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.go"), []byte("package foo\n"), 0o644))
+	name, err := packageName(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", name)
+}
+
+func TestPackageName_OnlyTests(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a_test.go"), []byte("package foo\n"), 0o644))
+	name, err := packageName(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", name)
+}
+
+func TestPackageName_Empty(t *testing.T) {
+	_, err := packageName(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestHelperSource(t *testing.T) {
+	source := helperSource("foo", "*MyStruct", "")
+	_, err := parser.ParseFile(token.NewFileSet(), helperFileName, source, 0)
+	require.NoError(t, err)
+	assert.Contains(t, source, "package foo\n")
+	assert.Contains(t, source, "func TestFuzzAllHelper(t *testing.T) {\n\tfuzzing.ToolHelper[*MyStruct](t)\n}")
+}
+
+func TestHelperSource_Opts(t *testing.T) {
+	source := helperSource("foo", "MyStruct", "fuzzOpts")
+	_, err := parser.ParseFile(token.NewFileSet(), helperFileName, source, 0)
+	require.NoError(t, err)
+	assert.Contains(t, source, "\tfuzzing.ToolHelper[MyStruct](t, fuzzOpts...)\n")
+}
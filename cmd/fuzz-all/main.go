@@ -0,0 +1,355 @@
+// Command fuzz-all works with the fuzz corpora of tests that use the
+// go-fuzz-all fuzzing package.
+//
+// Usage:
+//
+//	fuzz-all canonicalize -type MyStruct [-pkg dir] [-opts expr] path...
+//	fuzz-all decode -type MyStruct [-pkg dir] [-opts expr] [-format json|go] path...
+//	fuzz-all export -type MyStruct [-pkg dir] [-opts expr] [-format fields|bytes] -out dir path...
+//	fuzz-all fake -type MyInterface [-pkg dir] [-out file]
+//	fuzz-all layout -type MyStruct [-pkg dir] [-opts expr]
+//	fuzz-all migrate -from old.json -to new.json path...
+//	fuzz-all prune -type MyStruct [-pkg dir] [-opts expr] [-n] [-cache] path...
+//
+// The decode command prints every entry of the given `go test fuzz v1`
+// corpus files or directories, such as testdata/fuzz/FuzzMyFunc, as a value of
+// the fuzzed type. To learn the layout of the type, fuzz-all generates a small
+// test helper in the package that declares it and runs it with `go test`.
+// Generators and constructors passed to fuzzing.Fuzz change the layout, so
+// pass them with -opts, a Go expression of type []fuzzing.Option in the
+// package, such as a package level variable shared with the fuzz test.
+//
+// The canonicalize command rewrites corpus files with zero payloads behind nil
+// pointers, as the Go engine writes minimized crashers with whatever payload it
+// happened to mutate.
+//
+// The export command writes every entry of the given corpora into the field
+// path keyed format read by fuzzing.Fuzz from testdata/fuzz-all/FuzzMyFunc,
+// which, unlike the positional `go test fuzz v1` format, survives changes to
+// the fuzzed type and is easy to review. With -format bytes, it writes the
+// raw inputs read by fuzzing.ByteTarget instead, to seed byte oriented engines
+// such as go-fuzz and libFuzzer.
+//
+// The fake command generates a fake implementation of an interface, whose
+// results are drawn from the fuzz input by an embedded fuzzing.Fake.
+//
+// The layout command prints the layout of the fuzzed type as JSON. The migrate
+// command rewrites corpus files from one layout to another, after fields of
+// the fuzzed type were added, removed or reordered. Fields are matched by
+// path, new fields are zero filled and removed fields are dropped.
+//
+// The prune command removes corpus files that no longer decode, and files that
+// decode to the same value as another file. With -cache, the corpus of the same
+// fuzz test in the Go build cache is pruned too.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hugoklepsch/go-fuzz-all/fuzzing"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "canonicalize":
+		err = canonicalizeCmd(os.Args[2:])
+	case "decode":
+		err = decodeCmd(os.Args[2:])
+	case "export":
+		err = exportCmd(os.Args[2:])
+	case "fake":
+		err = fakeCmd(os.Args[2:])
+	case "layout":
+		err = layoutCmd(os.Args[2:])
+	case "migrate":
+		err = migrateCmd(os.Args[2:])
+	case "prune":
+		err = pruneCmd(os.Args[2:])
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "fuzz-all: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fuzz-all: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `usage: fuzz-all <command> [flags] [args]
+
+commands:
+	canonicalize  rewrite a go test fuzz v1 corpus with zero payloads behind nil pointers
+	decode        print the entries of a go test fuzz v1 corpus as typed values
+	export        convert a go test fuzz v1 corpus into field path keyed seeds
+	fake          generate a fake of an interface whose results are drawn from the fuzz input
+	layout        print the layout of the fuzzed type as JSON
+	migrate       rewrite a go test fuzz v1 corpus from one layout to another
+	prune         remove duplicate and undecodable entries from a go test fuzz v1 corpus
+
+Run "fuzz-all <command> -h" for the flags of a command.
+`)
+}
+
+// typeFlags are the flags shared by every command that runs the test helper.
+type typeFlags struct {
+	pkgDir   string
+	typeExpr string
+	optsExpr string
+}
+
+func (f *typeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.pkgDir, "pkg", ".", "directory of the package that declares the fuzzed type")
+	fs.StringVar(&f.typeExpr, "type", "", "the fuzzed type, as written in the package, such as MyStruct")
+	fs.StringVar(&f.optsExpr, "opts", "", "the options passed to fuzzing.Fuzz, as a Go expression of type []fuzzing.Option in the package")
+}
+
+func (f *typeFlags) check() error {
+	if f.typeExpr == "" {
+		return fmt.Errorf("-type is required")
+	}
+	return nil
+}
+
+func canonicalizeCmd(args []string) error {
+	fs := flag.NewFlagSet("canonicalize", flag.ExitOnError)
+	tf := typeFlags{}
+	tf.register(fs)
+	fs.Parse(args)
+	if err := tf.check(); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("canonicalize: at least one corpus file or directory is required")
+	}
+	paths, err := absPaths(fs.Args())
+	if err != nil {
+		return err
+	}
+	out, err := runHelper(tf, fuzzing.ToolRequest{
+		Command: "canonicalize",
+		Paths:   paths,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+func decodeCmd(args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	tf := typeFlags{}
+	tf.register(fs)
+	format := fs.String("format", "json", "output format, json or go")
+	fs.Parse(args)
+	if err := tf.check(); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("decode: at least one corpus file or directory is required")
+	}
+	paths, err := absPaths(fs.Args())
+	if err != nil {
+		return err
+	}
+	out, err := runHelper(tf, fuzzing.ToolRequest{
+		Command: "decode",
+		Format:  *format,
+		Paths:   paths,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+func exportCmd(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	tf := typeFlags{}
+	tf.register(fs)
+	outDir := fs.String("out", "", "seed directory to write to, such as testdata/fuzz-all/FuzzMyFunc")
+	format := fs.String("format", "fields", "seed format, fields or bytes")
+	fs.Parse(args)
+	if err := tf.check(); err != nil {
+		return err
+	}
+	if *outDir == "" {
+		return fmt.Errorf("export: -out is required")
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("export: at least one corpus file or directory is required")
+	}
+	paths, err := absPaths(append([]string{*outDir}, fs.Args()...))
+	if err != nil {
+		return err
+	}
+	out, err := runHelper(tf, fuzzing.ToolRequest{
+		Command: "export",
+		Format:  *format,
+		Paths:   paths[1:],
+		Dir:     paths[0],
+	})
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+func layoutCmd(args []string) error {
+	fs := flag.NewFlagSet("layout", flag.ExitOnError)
+	tf := typeFlags{}
+	tf.register(fs)
+	fs.Parse(args)
+	if err := tf.check(); err != nil {
+		return err
+	}
+	out, err := runHelper(tf, fuzzing.ToolRequest{Command: "layout"})
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+func migrateCmd(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fromPath := fs.String("from", "", "layout the corpus was written with, as printed by fuzz-all layout")
+	toPath := fs.String("to", "", "layout to migrate the corpus to, as printed by fuzz-all layout")
+	fs.Parse(args)
+	if *fromPath == "" || *toPath == "" {
+		return fmt.Errorf("migrate: -from and -to are required")
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("migrate: at least one corpus file or directory is required")
+	}
+	from, err := readLayout(*fromPath)
+	if err != nil {
+		return err
+	}
+	to, err := readLayout(*toPath)
+	if err != nil {
+		return err
+	}
+	for _, path := range fs.Args() {
+		migrated, err := fuzzing.MigrateCorpus(path, from, to)
+		if err != nil {
+			return err
+		}
+		for _, p := range migrated {
+			fmt.Printf("migrated %s\n", p)
+		}
+	}
+	return nil
+}
+
+func pruneCmd(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	tf := typeFlags{}
+	tf.register(fs)
+	dryRun := fs.Bool("n", false, "report what would be removed, without removing it")
+	cache := fs.Bool("cache", false, "also prune the corpus of the same fuzz tests in the Go build cache")
+	fs.Parse(args)
+	if err := tf.check(); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("prune: at least one corpus file or directory is required")
+	}
+	paths, err := absPaths(fs.Args())
+	if err != nil {
+		return err
+	}
+	if *cache {
+		cacheDirs, err := cacheCorpusDirs(tf.pkgDir, paths)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, cacheDirs...)
+	}
+	out, err := runHelper(tf, fuzzing.ToolRequest{
+		Command: "prune",
+		Paths:   paths,
+		DryRun:  *dryRun,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// cacheCorpusDirs returns the directories in the Go build cache that hold the
+// generated corpus of the fuzz tests whose checked in corpus is in corpusDirs.
+// Directories that do not exist are left out.
+func cacheCorpusDirs(pkgDir string, corpusDirs []string) ([]string, error) {
+	goCache, err := goOutput(pkgDir, "env", "GOCACHE")
+	if err != nil {
+		return nil, err
+	}
+	importPath, err := goOutput(pkgDir, "list", ".")
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{}
+	for _, corpusDir := range corpusDirs {
+		dir := filepath.Join(goCache, "fuzz", importPath, filepath.Base(corpusDir))
+		if _, err := os.Stat(dir); err == nil {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+func goOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func readLayout(path string) (fuzzing.Layout, error) {
+	layout := fuzzing.Layout{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return layout, err
+	}
+	if err := json.Unmarshal(data, &layout); err != nil {
+		return layout, fmt.Errorf("%s: %w", path, err)
+	}
+	return layout, nil
+}
+
+// absPaths makes paths absolute, as the test helper runs in the package
+// directory.
+func absPaths(paths []string) ([]string, error) {
+	abs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		abs = append(abs, absPath)
+	}
+	return abs, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/hugoklepsch/go-fuzz-all/fuzzing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadLayout(t *testing.T) {
+	type Foo struct {
+		S string
+		P *int
+	}
+	layout := fuzzing.LayoutOf[Foo]()
+	data, err := json.Marshal(layout)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "layout.json")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	read, err := readLayout(path)
+	require.NoError(t, err)
+	assert.Equal(t, layout, read)
+}
+
+func TestReadLayout_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "layout.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	_, err := readLayout(path)
+	assert.ErrorContains(t, err, path)
+}
+
+func TestAbsPaths(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	paths, err := absPaths([]string{"testdata", "/tmp"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(wd, "testdata"), "/tmp"}, paths)
+}
+
+func TestCacheCorpusDirs_Missing(t *testing.T) {
+	dirs, err := cacheCorpusDirs(".", []string{filepath.Join("testdata", "fuzz", "FuzzDoesNotExist")})
+	require.NoError(t, err)
+	assert.Empty(t, dirs)
+}
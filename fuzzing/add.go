@@ -5,31 +5,65 @@ import (
 	"reflect"
 )
 
-func Add[T any](f TestingF, t T) {
-	tValue := reflect.ValueOf(t)
-
-	fieldsTraverser := anyToFieldsTraverser{}
-	fieldsTraverser.traverseValue(tValue)
-
-	f.Add(fieldsTraverser.fields...)
+// Add adds t to the corpus of f. Pass the options that bind generators, if
+// any, that are passed to Fuzz.
+func Add[T any](f TestingF, t T, opts ...Option) {
+	AddNamed(f, "", t, opts...)
 }
 
 type anyToFieldsTraverser struct {
 	fields      []any
 	fieldsTypes []reflect.Type
+	fieldsPaths []string
+	// path is the field path of the value currently being traversed.
+	path string
+	// gens are the generators bound to fields, which take a []byte each.
+	gens genBindings
 }
 
 func (a *anyToFieldsTraverser) addValue(i any) {
 	a.fields = append(a.fields, i)
 	a.fieldsTypes = append(a.fieldsTypes, reflect.TypeOf(i))
+	a.fieldsPaths = append(a.fieldsPaths, a.path)
 }
 
 func (a *anyToFieldsTraverser) addZeroValue(t reflect.Type) {
 	a.fields = append(a.fields, reflect.Zero(t).Interface())
 	a.fieldsTypes = append(a.fieldsTypes, t)
+	a.fieldsPaths = append(a.fieldsPaths, a.path)
+}
+
+// enterField sets the path to the struct field name, and returns a function
+// that restores the previous path.
+func (a *anyToFieldsTraverser) enterField(name string) func() {
+	parent := a.path
+	a.path = joinFieldPath(parent, name)
+	return func() { a.path = parent }
+}
+
+// enterPointer sets the path to what a pointer of type t points at, and
+// returns a function that restores the previous path.
+func (a *anyToFieldsTraverser) enterPointer(t reflect.Type) func() {
+	parent := a.path
+	a.path = pointeeFieldPath(parent, t)
+	return func() { a.path = parent }
+}
+
+// addGenerated adds the []byte of a field bound to a generator, and reports
+// whether the field of type t is bound. Generators cannot be inverted, so
+// the bytes are always empty.
+func (a *anyToFieldsTraverser) addGenerated(t reflect.Type) bool {
+	if a.gens.lookup(a.path, t) == nil {
+		return false
+	}
+	a.addValue([]byte{})
+	return true
 }
 
 func (a *anyToFieldsTraverser) traverseValue(value reflect.Value) {
+	if a.addGenerated(value.Type()) {
+		return
+	}
 	switch value.Kind() {
 	case reflect.Bool:
 		a.addValue(value.Bool())
@@ -104,11 +138,13 @@ func (a *anyToFieldsTraverser) traverseValue(value reflect.Value) {
 		// subsequent value(s) are the fields from what the pointer points at.
 		isSet := !value.IsNil()
 		a.addValue(isSet)
+		leave := a.enterPointer(value.Type())
 		if isSet {
 			a.traverseValue(value.Elem())
 		} else {
 			a.traverseType(value.Type().Elem())
 		}
+		leave()
 		break
 	case reflect.Slice:
 		// TODO Can we even do anything?
@@ -124,7 +160,9 @@ func (a *anyToFieldsTraverser) traverseValue(value reflect.Value) {
 			if !value.Type().Field(i).IsExported() {
 				continue
 			}
+			leave := a.enterField(value.Type().Field(i).Name)
 			a.traverseValue(iValue)
+			leave()
 		}
 		break
 	case reflect.UnsafePointer:
@@ -136,6 +174,9 @@ func (a *anyToFieldsTraverser) traverseValue(value reflect.Value) {
 }
 
 func (a *anyToFieldsTraverser) traverseType(t reflect.Type) {
+	if a.addGenerated(t) {
+		return
+	}
 	switch t.Kind() {
 	case reflect.Bool:
 		fallthrough
@@ -195,7 +236,9 @@ func (a *anyToFieldsTraverser) traverseType(t reflect.Type) {
 		// subsequent value(s) are the fields from what the pointer points at.
 		isSet := false
 		a.addValue(isSet)
+		leave := a.enterPointer(t)
 		a.traverseType(t.Elem())
+		leave()
 		break
 	case reflect.Slice:
 		// TODO Can we even do anything?
@@ -211,7 +254,9 @@ func (a *anyToFieldsTraverser) traverseType(t reflect.Type) {
 			if !iStructField.IsExported() {
 				continue
 			}
+			leave := a.enterField(iStructField.Name)
 			a.traverseType(iStructField.Type)
+			leave()
 		}
 		break
 	case reflect.UnsafePointer:
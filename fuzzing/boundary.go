@@ -0,0 +1,152 @@
+package fuzzing
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// WithBoundarySeeds adds a seed corpus of interesting values, generated from
+// the layout of the fuzzed type, before fuzzing starts. See BoundarySeeds.
+func WithBoundarySeeds() Option {
+	return func(c *config) {
+		c.boundarySeeds = true
+	}
+}
+
+// addBoundarySeeds adds the flattened fields of BoundarySeeds to the corpus.
+func addBoundarySeeds(f TestingF, t reflect.Type, gens genBindings) {
+	for _, seed := range boundaryFieldSeeds(t, gens) {
+		addFields(f, "", seed)
+	}
+}
+
+// BoundarySeeds returns interesting values of T: the zero value, the value
+// with every pointer set, and, one field at a time, values such as -1, the
+// minimum and maximum of the exact integer width, NaN, ±Inf, -0.0, long
+// strings and invalid UTF-8. Fields are varied one at a time, with every other
+// field zero, rather than combined.
+func BoundarySeeds[T any]() []T {
+	fieldSeeds := boundaryFieldSeeds(reflect.TypeFor[T](), nil)
+	seeds := make([]T, 0, len(fieldSeeds))
+	for _, fields := range fieldSeeds {
+		t, err := decodeFields[T](fields)
+		if err != nil {
+			panic(err)
+		}
+		seeds = append(seeds, t)
+	}
+	return seeds
+}
+
+func boundaryFieldSeeds(t reflect.Type, gens genBindings) [][]any {
+	zeroTraverser := anyToFieldsTraverser{gens: gens}
+	zeroTraverser.traverseType(t)
+	zero := zeroTraverser.fields
+
+	setTraverser := anyToFieldsTraverser{gens: gens}
+	setTraverser.traverseValue(allPointersSet(t))
+	set := setTraverser.fields
+
+	seeds := [][]any{}
+	seen := map[string]bool{}
+	addSeed := func(seed []any) {
+		key := fmt.Sprintf("%#v", seed)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		seeds = append(seeds, seed)
+	}
+	addSeed(zero)
+	addSeed(set)
+	for i := range set {
+		isSet, isBool := set[i].(bool)
+		if isBool && isSet && zero[i] == false {
+			// A pointer presence bool, so try the pointer unset, with its
+			// payload zero as everything behind it is zero.
+			seed := append([]any{}, set...)
+			seed[i] = false
+			addSeed(seed)
+			continue
+		}
+		for _, val := range boundaryValues(setTraverser.fieldsTypes[i]) {
+			seed := append([]any{}, set...)
+			seed[i] = val
+			addSeed(seed)
+		}
+	}
+	return seeds
+}
+
+// allPointersSet returns the zero value of t, except that every pointer is set.
+func allPointersSet(t reflect.Type) reflect.Value {
+	value := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Pointer:
+		value.Set(reflect.New(t.Elem()))
+		value.Elem().Set(allPointersSet(t.Elem()))
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			value.Field(i).Set(allPointersSet(t.Field(i).Type))
+		}
+	}
+	return value
+}
+
+// boundaryValues returns the interesting non-zero values of a flattened field
+// of type t.
+func boundaryValues(t reflect.Type) []any {
+	longString := strings.Repeat("a", 1024)
+	switch t.Kind() {
+	case reflect.Bool:
+		return []any{true}
+	case reflect.Int:
+		return []any{1, -1, math.MinInt, math.MaxInt}
+	case reflect.Int8:
+		return []any{int8(1), int8(-1), int8(math.MinInt8), int8(math.MaxInt8)}
+	case reflect.Int16:
+		return []any{int16(1), int16(-1), int16(math.MinInt16), int16(math.MaxInt16)}
+	case reflect.Int32:
+		return []any{int32(1), int32(-1), int32(math.MinInt32), int32(math.MaxInt32)}
+	case reflect.Int64:
+		return []any{int64(1), int64(-1), int64(math.MinInt64), int64(math.MaxInt64)}
+	case reflect.Uint:
+		return []any{uint(1), uint(math.MaxUint)}
+	case reflect.Uint8:
+		return []any{uint8(1), uint8(math.MaxUint8)}
+	case reflect.Uint16:
+		return []any{uint16(1), uint16(math.MaxUint16)}
+	case reflect.Uint32:
+		return []any{uint32(1), uint32(math.MaxUint32)}
+	case reflect.Uint64:
+		return []any{uint64(1), uint64(math.MaxUint64)}
+	case reflect.Uintptr:
+		return []any{uintptr(1), ^uintptr(0)}
+	case reflect.Float32:
+		return []any{
+			float32(1), float32(-1), float32(math.Copysign(0, -1)), float32(math.NaN()),
+			float32(math.Inf(1)), float32(math.Inf(-1)), float32(math.MaxFloat32), float32(math.SmallestNonzeroFloat32),
+		}
+	case reflect.Float64:
+		return []any{
+			1.0, -1.0, math.Copysign(0, -1), math.NaN(),
+			math.Inf(1), math.Inf(-1), math.MaxFloat64, math.SmallestNonzeroFloat64,
+		}
+	case reflect.Complex64:
+		return []any{complex64(complex(1, -1)), complex64(complex(math.Inf(1), math.NaN()))}
+	case reflect.Complex128:
+		return []any{complex(1, -1), complex(math.Inf(1), math.NaN())}
+	case reflect.String:
+		return []any{" ", "\x00", "\xff\xfe", "日本語", longString}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return []any{[]byte{}, []byte{0}, []byte{0xff}, []byte(longString)}
+		}
+	}
+	return nil
+}
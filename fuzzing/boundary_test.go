@@ -0,0 +1,84 @@
+package fuzzing
+
+import (
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBoundaryFieldSeeds_OneFieldAtATime(t *testing.T) {
+	type Foo struct {
+		B bool
+		I int8
+	}
+	assert.Equal(t, [][]any{
+		{false, int8(0)},
+		{true, int8(0)},
+		{false, int8(1)},
+		{false, int8(-1)},
+		{false, int8(math.MinInt8)},
+		{false, int8(math.MaxInt8)},
+	}, boundaryFieldSeeds(reflect.TypeFor[Foo](), nil))
+}
+
+func TestBoundaryFieldSeeds_Pointers(t *testing.T) {
+	type Nested struct {
+		U *uint8
+	}
+	type Foo struct {
+		N *Nested
+	}
+	assert.Equal(t, [][]any{
+		// Everything nil.
+		{false, false, uint8(0)},
+		// Everything set.
+		{true, true, uint8(0)},
+		// One pointer at a time unset.
+		{false, true, uint8(0)},
+		{true, false, uint8(0)},
+		// Payloads are varied behind set pointers.
+		{true, true, uint8(1)},
+		{true, true, uint8(math.MaxUint8)},
+	}, boundaryFieldSeeds(reflect.TypeFor[Foo](), nil))
+}
+
+func TestBoundarySeeds(t *testing.T) {
+	type Foo struct {
+		S string
+		F *float64
+	}
+	seeds := BoundarySeeds[Foo]()
+	assert.Contains(t, seeds, Foo{})
+	assert.Contains(t, seeds, Foo{F: ptr(0.0)})
+	assert.Contains(t, seeds, Foo{S: "\xff\xfe", F: ptr(0.0)})
+	assert.Contains(t, seeds, Foo{F: ptr(math.Inf(-1))})
+
+	negativeZero, nan := false, false
+	for _, seed := range seeds {
+		if seed.F != nil && *seed.F == 0 && math.Signbit(*seed.F) {
+			negativeZero = true
+		}
+		if seed.F != nil && math.IsNaN(*seed.F) {
+			nan = true
+		}
+	}
+	assert.True(t, negativeZero)
+	assert.True(t, nan)
+}
+
+func TestFuzz_WithBoundarySeeds(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	gomock.InOrder(
+		mockF.EXPECT().Add(false),
+		mockF.EXPECT().Add(true),
+		mockF.EXPECT().Fuzz(gomock.Any()),
+	)
+	Fuzz(mockF, func(t *testing.T, b bool) {}, WithBoundarySeeds())
+}
@@ -0,0 +1,127 @@
+package fuzzing
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// ByteTarget adapts the fuzz target fn to the `func(data []byte) int` entry
+// point of byte oriented fuzzing engines, such as dvyukov/go-fuzz, and
+// libFuzzer through go114-fuzz-build. data is decoded into a T with a Source,
+// one value per slot of the layout of T, and pointers are encoded the same way
+// as by Fuzz: a bool for whether the pointer is set, followed by what it
+// points at.
+//
+// Like Fuzz, the adapter returns -1, which tells the engine to not add the
+// input to its corpus, for inputs rejected by a precondition and for
+// non-canonical inputs, unless AllowNonCanonicalInputs is passed. Otherwise,
+// it returns what fn returns: 1 to raise the priority of the input, or 0.
+// Panics are left to the engine to report. ByteTarget panics if opts have a
+// constructor, which it does not support.
+//
+//	func FuzzMyFunc(data []byte) int {
+//		return fuzzing.ByteTarget(func(myStruct MyStruct) int {
+//			MyFunc(myStruct)
+//			return 0
+//		})(data)
+//	}
+func ByteTarget[T any](fn func(T) int, opts ...Option) func([]byte) int {
+	cfg := newConfig(opts)
+	slotTypes := byteSlotTypes[T](cfg)
+	return func(data []byte) int {
+		value, builder := decodeSource[T](slotTypes, NewSource(data), cfg.gens)
+		if builder.nonCanonical && !cfg.allowNonCanonical {
+			return -1
+		}
+		if builder.rejected || !cfg.accepts(value) {
+			return -1
+		}
+		return fn(value)
+	}
+}
+
+// DecodeBytes decodes data into a T the same way as ByteTarget. Pass the
+// options passed to ByteTarget, as generators change the layout.
+func DecodeBytes[T any](data []byte, opts ...Option) T {
+	cfg := newConfig(opts)
+	value, _ := decodeSource[T](byteSlotTypes[T](cfg), NewSource(data), cfg.gens)
+	return value
+}
+
+// byteSlotTypes returns the layout of T with the generators of cfg, and
+// panics if they do not apply to T. Constructors are not supported, and
+// panic too, rather than being ignored along with the invariants they
+// enforce.
+func byteSlotTypes[T any](cfg *config) []reflect.Type {
+	if cfg.constructor != nil {
+		panic(fmt.Errorf("fuzzing: byte targets do not support constructors, so %s cannot build %v", cfg.constructor.name, reflect.TypeFor[T]()))
+	}
+	if err := cfg.gens.check(reflect.TypeFor[T]()); err != nil {
+		panic(fmt.Errorf("fuzzing: %w", err))
+	}
+	fieldsTraverser := anyToFieldsTraverser{gens: cfg.gens}
+	fieldsTraverser.traverseType(reflect.TypeFor[T]())
+	return fieldsTraverser.fieldsTypes
+}
+
+// decodeSource decodes a T with the layout slotTypes from source. The builder
+// is returned to tell whether the input was canonical and accepted by the
+// generators in gens.
+func decodeSource[T any](slotTypes []reflect.Type, source *Source, gens genBindings) (T, *buildAnyTraverser) {
+	value, builder := decodeSourceValue(reflect.TypeFor[T](), slotTypes, source, gens)
+	return value.Interface().(T), builder
+}
+
+// decodeSourceValue decodes a value of type t with the layout slotTypes from
+// source, like decodeSource.
+func decodeSourceValue(t reflect.Type, slotTypes []reflect.Type, source *Source, gens genBindings) (reflect.Value, *buildAnyTraverser) {
+	fields := make([]reflect.Value, 0, len(slotTypes))
+	for _, slotType := range slotTypes {
+		fields = append(fields, source.value(slotType))
+	}
+	builder := &buildAnyTraverser{
+		fields: fields,
+		gens:   gens,
+	}
+	return builder.traverseType(t), builder
+}
+
+// EncodeBytes encodes t into the input that ByteTarget decodes back to t, to
+// seed the corpus of byte oriented fuzzing engines. Pass the options passed to
+// ByteTarget, as generators change the layout. Generated values cannot be
+// encoded, so fields bound to generators are encoded as empty generator
+// input, like Add does.
+func EncodeBytes[T any](t T, opts ...Option) []byte {
+	cfg := newConfig(opts)
+	byteSlotTypes[T](cfg)
+	fieldsTraverser := anyToFieldsTraverser{gens: cfg.gens}
+	fieldsTraverser.traverseValue(reflect.ValueOf(t))
+	data := []byte{}
+	for _, field := range fieldsTraverser.fields {
+		data = appendValue(data, reflect.ValueOf(field))
+	}
+	return data
+}
+
+// WriteBytesFile writes the EncodeBytes encoding of t into the corpus
+// directory dir of a byte oriented fuzzing engine. Like libFuzzer, the file is
+// named after the SHA-1 hash of its content. Pass the options passed to
+// ByteTarget, which panic like those of EncodeBytes if they do not apply to T.
+// The path of the written file is returned.
+func WriteBytesFile[T any](dir string, t T, opts ...Option) (string, error) {
+	return writeBytesFile(dir, EncodeBytes(t, opts...))
+}
+
+func writeBytesFile(dir string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%x", sha1.Sum(data)))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,131 @@
+package fuzzing
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type bytesNested struct {
+	F float64
+}
+
+type bytesFoo struct {
+	S string
+	I *int
+	N *bytesNested
+	B bool
+}
+
+func TestEncodeBytes_RoundTrip(t *testing.T) {
+	for _, foo := range []bytesFoo{
+		{},
+		{S: "foo", I: ptr(-42), B: true},
+		{N: &bytesNested{F: 3.14}},
+	} {
+		assert.Equal(t, foo, DecodeBytes[bytesFoo](EncodeBytes(foo)))
+	}
+}
+
+type bytesGenFoo struct {
+	S     string
+	Level int
+	N     int
+}
+
+func TestEncodeBytes_WithGen(t *testing.T) {
+	opts := []Option{WithFieldGen("Level", IntRange(1, 5))}
+	data := EncodeBytes(bytesGenFoo{S: "foo", Level: 4, N: 7}, opts...)
+	// The generator draws from empty input, so Level is the lowest level.
+	assert.Equal(t, bytesGenFoo{S: "foo", Level: 1, N: 7}, DecodeBytes[bytesGenFoo](data, opts...))
+	assert.Panics(t, func() { DecodeBytes[bytesGenFoo](data, WithFieldGen("Missing", IntRange(1, 5))) })
+}
+
+func TestByteTarget_WithConstructor(t *testing.T) {
+	c, err := NewConstructor[*ctorAccount](newCtorAccount)
+	require.NoError(t, err)
+	message := "fuzzing: byte targets do not support constructors, so fuzzing.newCtorAccount cannot build *fuzzing.ctorAccount"
+	assert.PanicsWithError(t, message, func() {
+		ByteTarget(func(account *ctorAccount) int { return 0 }, WithConstructor(c))
+	})
+	assert.PanicsWithError(t, message, func() { DecodeBytes[*ctorAccount](nil, WithConstructor(c)) })
+	assert.PanicsWithError(t, message, func() { EncodeBytes(&ctorAccount{}, WithConstructor(c)) })
+}
+
+func TestByteTarget(t *testing.T) {
+	got := []bytesFoo{}
+	target := ByteTarget(func(foo bytesFoo) int {
+		got = append(got, foo)
+		return 1
+	})
+	foo := bytesFoo{S: "foo", I: ptr(42)}
+	assert.Equal(t, 1, target(EncodeBytes(foo)))
+	// Any input decodes to some value.
+	assert.Equal(t, 1, target(nil))
+	assert.Equal(t, []bytesFoo{foo, {}}, got)
+}
+
+func TestByteTarget_NonCanonical(t *testing.T) {
+	// S is empty, I is unset but has a non-zero payload.
+	data := []byte{0x00, 0x00, 0x2a, 0, 0, 0, 0, 0, 0, 0}
+	calls := 0
+	target := func(foo bytesFoo) int {
+		calls++
+		return 0
+	}
+	assert.Equal(t, -1, ByteTarget(target)(data))
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, 0, ByteTarget(target, AllowNonCanonicalInputs())(data))
+	assert.Equal(t, 1, calls)
+}
+
+func TestByteTarget_Precondition(t *testing.T) {
+	target := ByteTarget(func(foo bytesFoo) int {
+		return 0
+	}, WithPrecondition(func(foo bytesFoo) bool { return foo.B }))
+	assert.Equal(t, -1, target(EncodeBytes(bytesFoo{})))
+	assert.Equal(t, 0, target(EncodeBytes(bytesFoo{B: true})))
+}
+
+func TestWriteBytesFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "corpus")
+	foo := bytesFoo{S: "foo"}
+	path, err := WriteBytesFile(dir, foo)
+	require.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, EncodeBytes(foo), data)
+}
+
+func TestWriteBytesFile_WithGen(t *testing.T) {
+	opts := []Option{WithFieldGen("Level", IntRange(1, 5))}
+	foo := bytesGenFoo{S: "foo", Level: 4, N: 7}
+	path, err := WriteBytesFile(t.TempDir(), foo, opts...)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, EncodeBytes(foo, opts...), data)
+	assert.NotEqual(t, EncodeBytes(foo), data)
+}
+
+func TestRunTool_ExportBytes(t *testing.T) {
+	corpusDir := t.TempDir()
+	outDir := t.TempDir()
+	writeCorpusFile(t, corpusDir, "a", "go test fuzz v1\nstring(\"foo\")\nbool(true)\nint(42)\n")
+
+	out := &bytes.Buffer{}
+	require.NoError(t, runTool[toolFoo](ToolRequest{Command: "export", Format: "bytes", Paths: []string{corpusDir}, Dir: outDir}, out))
+	files, err := os.ReadDir(outDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(filepath.Join(outDir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Equal(t, toolFoo{S: "foo", P: ptr(42)}, DecodeBytes[toolFoo](data))
+
+	err = runTool[toolFoo](ToolRequest{Command: "export", Format: "xml", Paths: []string{corpusDir}, Dir: outDir}, out)
+	assert.EqualError(t, err, `unknown format "xml"`)
+}
@@ -0,0 +1,49 @@
+package fuzzing
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+// CanonicalizeCorpus rewrites the `go test fuzz v1` files at path, a file or
+// a directory such as testdata/fuzz/FuzzMyFunc, in their canonical encoding,
+// with zero payloads behind nil pointers. The Go engine writes minimized
+// crashers with whatever payload it happened to mutate. Files keep their
+// names, and files that do not decode are left alone. Pass the options passed
+// to Fuzz, as generators and constructors change the layout. The paths of the
+// rewritten files are returned.
+func CanonicalizeCorpus[T any](path string, opts ...Option) ([]string, error) {
+	cfg := newConfig(opts)
+	t, err := cfg.decodeType(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readCorpus(path, t, cfg.gens)
+	if err != nil {
+		return nil, err
+	}
+	rewritten := []string{}
+	for _, entry := range entries {
+		if entry.err != nil {
+			continue
+		}
+		data, err := os.ReadFile(entry.path)
+		if err != nil {
+			return rewritten, err
+		}
+		canonical, err := marshalCorpusFile(canonicalSlots(t, cfg.gens, entry.vals))
+		if err != nil {
+			return rewritten, fmt.Errorf("%s: %w", entry.path, err)
+		}
+		if bytes.Equal(data, canonical) {
+			continue
+		}
+		if err := os.WriteFile(entry.path, canonical, 0o644); err != nil {
+			return rewritten, err
+		}
+		rewritten = append(rewritten, entry.path)
+	}
+	return rewritten, nil
+}
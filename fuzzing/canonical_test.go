@@ -0,0 +1,66 @@
+package fuzzing
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type canonicalFoo struct {
+	S string
+	P *int
+}
+
+func TestCanonicalizeCorpus(t *testing.T) {
+	dir := t.TempDir()
+	canonical := "go test fuzz v1\nstring(\"foo\")\nbool(false)\nint(0)\n"
+	a := writeCorpusFile(t, dir, "a", canonical)
+	// The payload behind the nil pointer is thrown away when decoding.
+	b := writeCorpusFile(t, dir, "b", "go test fuzz v1\nstring(\"foo\")\nbool(false)\nint(42)\n")
+	c := writeCorpusFile(t, dir, "c", "go test fuzz v1\nstring(\"foo\")\nbool(true)\nint(42)\n")
+	d := writeCorpusFile(t, dir, "d", "go test fuzz v1\nstring(\"foo\")\n")
+
+	rewritten, err := CanonicalizeCorpus[canonicalFoo](dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{b}, rewritten)
+
+	data, err := os.ReadFile(b)
+	require.NoError(t, err)
+	assert.Equal(t, canonical, string(data))
+	for path, want := range map[string]string{
+		a: canonical,
+		c: "go test fuzz v1\nstring(\"foo\")\nbool(true)\nint(42)\n",
+		d: "go test fuzz v1\nstring(\"foo\")\n",
+	} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(data))
+	}
+}
+
+func TestCanonicalizeCorpus_Missing(t *testing.T) {
+	_, err := CanonicalizeCorpus[canonicalFoo](filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+type canonicalGenFoo struct {
+	P *struct {
+		R io.Reader
+	}
+}
+
+func TestCanonicalizeCorpus_WithGen(t *testing.T) {
+	dir := t.TempDir()
+	// The generator input behind the nil pointer is thrown away too.
+	a := writeCorpusFile(t, dir, "a", "go test fuzz v1\nbool(false)\n[]byte(\"abc\")\n")
+
+	rewritten, err := CanonicalizeCorpus[canonicalGenFoo](dir, WithGen(ReaderGen(nil)))
+	require.NoError(t, err)
+	assert.Equal(t, []string{a}, rewritten)
+	data, err := os.ReadFile(a)
+	require.NoError(t, err)
+	assert.Equal(t, "go test fuzz v1\nbool(false)\n[]byte(\"\")\n", string(data))
+}
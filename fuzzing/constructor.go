@@ -0,0 +1,163 @@
+package fuzzing
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Constructor builds values of T by calling a constructor function, for types
+// with private state or invariants that decoding field by field would bypass.
+// Fuzz decodes the arguments of the constructor instead of T, and skips inputs
+// for which the constructor returns an error.
+type Constructor[T any] struct {
+	c *constructor
+}
+
+// constructor is the type-erased part of a Constructor, kept in the config.
+type constructor struct {
+	fn reflect.Value
+	// name is the package qualified name of the constructor function.
+	name string
+	// args is a struct with a field Arg0, Arg1 and so on for every parameter
+	// of the constructor, and is decoded instead of the constructed type.
+	args       reflect.Type
+	result     reflect.Type
+	returnsErr bool
+}
+
+var errorType = reflect.TypeFor[error]()
+
+// NewConstructor returns a Constructor that builds values of T with fn, which
+// must be a function that returns either a T, or a T and an error, such as
+// func NewAccount(id string, limit int) (*Account, error). Variadic functions
+// are not supported.
+func NewConstructor[T any](fn any) (*Constructor[T], error) {
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func || fnValue.IsNil() {
+		return nil, fmt.Errorf("fuzzing: constructor must be a func, got %T", fn)
+	}
+	fnType := fnValue.Type()
+	if fnType.IsVariadic() {
+		return nil, fmt.Errorf("fuzzing: constructor %v must not be variadic", fnType)
+	}
+	result := reflect.TypeFor[T]()
+	returnsErr := fnType.NumOut() == 2 && fnType.Out(1) == errorType
+	if fnType.NumOut() == 0 || fnType.Out(0) != result || fnType.NumOut() > 1 && !returnsErr || fnType.NumOut() > 2 {
+		return nil, fmt.Errorf("fuzzing: constructor %v must return %v, or %v and error", fnType, result, result)
+	}
+	fields := make([]reflect.StructField, 0, fnType.NumIn())
+	for i := 0; i < fnType.NumIn(); i++ {
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("Arg%d", i),
+			Type: fnType.In(i),
+		})
+	}
+	name := runtime.FuncForPC(fnValue.Pointer()).Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	return &Constructor[T]{c: &constructor{
+		fn:         fnValue,
+		name:       name,
+		args:       reflect.StructOf(fields),
+		result:     result,
+		returnsErr: returnsErr,
+	}}, nil
+}
+
+// WithConstructor makes Fuzz build the value passed to the fuzz target by
+// calling the constructor c with fuzzed arguments. In the layout, the
+// arguments are the fields Arg0, Arg1 and so on, and so they are in typed
+// seeds and in field paths passed to WithFieldGen. Inputs for which the
+// constructor returns an error are skipped like inputs rejected by a
+// precondition.
+func WithConstructor[T any](c *Constructor[T]) Option {
+	return func(cfg *config) {
+		cfg.constructor = c.c
+	}
+}
+
+// Add adds the arguments args of the constructor to the corpus of f. Pass the
+// options passed to Fuzz, as generators bound to the arguments change the
+// layout.
+func (c *Constructor[T]) Add(f TestingF, args []any, opts ...Option) {
+	cfg := newConfig(opts)
+	if cfg.constructor != nil && cfg.constructor != c.c {
+		f.Fatalf("fuzzing: the options have the constructor %s, not %s", cfg.constructor.name, c.c.name)
+		return
+	}
+	if err := cfg.gens.check(c.c.args); err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	fnType := c.c.fn.Type()
+	if len(args) != fnType.NumIn() {
+		f.Fatalf("fuzzing: %s takes %d arguments, got %d", c.c.name, fnType.NumIn(), len(args))
+		return
+	}
+	argsValue := reflect.New(c.c.args).Elem()
+	for i, arg := range args {
+		if arg == nil {
+			// Leave nil pointers, interfaces and the like zero.
+			continue
+		}
+		argValue := reflect.ValueOf(arg)
+		if !argValue.Type().AssignableTo(fnType.In(i)) {
+			f.Fatalf("fuzzing: argument %d of %s must be %v, got %T", i, c.c.name, fnType.In(i), arg)
+			return
+		}
+		argsValue.Field(i).Set(argValue)
+	}
+	fieldsTraverser := anyToFieldsTraverser{gens: cfg.gens}
+	fieldsTraverser.traverseValue(argsValue)
+	addFields(f, "", fieldsTraverser.fields)
+}
+
+// call calls the constructor with the fields of args.
+func (c *constructor) call(args reflect.Value) (reflect.Value, error) {
+	in := make([]reflect.Value, 0, args.NumField())
+	for i := 0; i < args.NumField(); i++ {
+		in = append(in, args.Field(i))
+	}
+	out := c.fn.Call(in)
+	if c.returnsErr && !out[1].IsNil() {
+		return out[0], out[1].Interface().(error)
+	}
+	return out[0], nil
+}
+
+// construct calls c with args. A panic of c is reported to t like a panic of
+// the fuzz target, with input, and panicked is true.
+func construct(t failureReporter, seedName string, c *constructor, args reflect.Value, input any) (value reflect.Value, panicked bool, err error) {
+	t.Helper()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			t.Errorf("constructor %s panicked: %v\n%s\n%s", c.name, recovered, describeFailingInput(seedName, input), debug.Stack())
+			panicked = true
+		}
+	}()
+	value, err = c.call(args)
+	return value, false, err
+}
+
+// constructorCall is the input of a fuzz target that uses a constructor, as
+// reported on failure.
+type constructorCall struct {
+	c    *constructor
+	args reflect.Value
+}
+
+// describe describes the arguments of the call by field path, and returns the
+// call as Go code.
+func (call constructorCall) describe() (description string, code string) {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "decoded arguments of %s:", call.c.name)
+	describer := fieldPathDescriber{sb: sb}
+	describer.describeValue("", call.args)
+	args := make([]string, 0, call.args.NumField())
+	for i := 0; i < call.args.NumField(); i++ {
+		args = append(args, GoLiteral(call.args.Field(i).Interface()))
+	}
+	return sb.String(), fmt.Sprintf("%s(%s)", call.c.name, strings.Join(args, ", "))
+}
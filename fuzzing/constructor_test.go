@@ -0,0 +1,198 @@
+package fuzzing
+
+import (
+	"errors"
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"reflect"
+	"testing"
+)
+
+type ctorAccount struct {
+	id    string
+	limit int
+}
+
+func newCtorAccount(id string, limit *int) (*ctorAccount, error) {
+	if limit == nil {
+		return &ctorAccount{id: id}, nil
+	}
+	if *limit < 0 {
+		return nil, errors.New("negative limit")
+	}
+	return &ctorAccount{id: id, limit: *limit}, nil
+}
+
+func TestNewConstructor_Invalid(t *testing.T) {
+	_, err := NewConstructor[*ctorAccount]("newCtorAccount")
+	assert.EqualError(t, err, "fuzzing: constructor must be a func, got string")
+	_, err = NewConstructor[*ctorAccount](func(ids ...string) *ctorAccount { return nil })
+	assert.EqualError(t, err, "fuzzing: constructor func(...string) *fuzzing.ctorAccount must not be variadic")
+	_, err = NewConstructor[ctorAccount](newCtorAccount)
+	assert.EqualError(t, err, "fuzzing: constructor func(string, *int) (*fuzzing.ctorAccount, error) must return fuzzing.ctorAccount, or fuzzing.ctorAccount and error")
+	_, err = NewConstructor[*ctorAccount](func() (*ctorAccount, bool) { return nil, false })
+	assert.Error(t, err)
+	_, err = NewConstructor[*ctorAccount](func() *ctorAccount { return nil })
+	assert.NoError(t, err)
+}
+
+func TestConstructor_Add(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	c, err := NewConstructor[*ctorAccount](newCtorAccount)
+	require.NoError(t, err)
+	assert.Equal(t, "fuzzing.newCtorAccount", c.c.name)
+	assert.Equal(t, []LayoutField{
+		{Path: "Arg0", Type: "string"},
+		{Path: "Arg1", Type: "bool"},
+		{Path: "*Arg1", Type: "int"},
+	}, layoutOf(c.c.args, nil).Fields)
+
+	gomock.InOrder(
+		mockF.EXPECT().Add("alice", true, 5),
+		mockF.EXPECT().Add("bob", false, 0),
+		mockF.EXPECT().Fatalf("fuzzing: %s takes %d arguments, got %d", "fuzzing.newCtorAccount", 2, 1),
+		mockF.EXPECT().Fatalf("fuzzing: argument %d of %s must be %v, got %T", 1, "fuzzing.newCtorAccount", reflect.TypeFor[*int](), 5),
+	)
+	c.Add(mockF, []any{"alice", ptr(5)})
+	c.Add(mockF, []any{"bob", nil})
+	c.Add(mockF, []any{"carol"})
+	c.Add(mockF, []any{"carol", 5})
+}
+
+func TestFuzz_WithConstructor(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	c, err := NewConstructor[*ctorAccount](newCtorAccount)
+	require.NoError(t, err)
+	var target func(*testing.T, string, bool, int)
+	mockF.EXPECT().Fuzz(gomock.Any()).Do(func(fuzzTarget any) {
+		target = fuzzTarget.(func(*testing.T, string, bool, int))
+	})
+	var called []*ctorAccount
+	Fuzz(mockF, func(t *testing.T, account *ctorAccount) {
+		called = append(called, account)
+	}, WithConstructor(c))
+
+	target(t, "alice", true, 5)
+	target(t, "bob", false, 0)
+	skipped := false
+	t.Run("rejected", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		target(t, "eve", true, -1)
+	})
+	assert.True(t, skipped)
+	assert.Equal(t, []*ctorAccount{{id: "alice", limit: 5}, {id: "bob"}}, called)
+}
+
+func TestFuzz_WithConstructor_WithFieldGen(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	c, err := NewConstructor[*ctorAccount](newCtorAccount)
+	require.NoError(t, err)
+	opts := []Option{WithConstructor(c), WithFieldGen("Arg0", OneOf(Const("alice"), Const("bob")))}
+	// The generated id is encoded as empty generator input.
+	mockF.EXPECT().Add([]byte{}, true, 5)
+	c.Add(mockF, []any{"carol", ptr(5)}, opts...)
+
+	var target func(*testing.T, []byte, bool, int)
+	mockF.EXPECT().Fuzz(gomock.Any()).Do(func(fuzzTarget any) {
+		target = fuzzTarget.(func(*testing.T, []byte, bool, int))
+	})
+	var called []*ctorAccount
+	Fuzz(mockF, func(t *testing.T, account *ctorAccount) {
+		called = append(called, account)
+	}, opts...)
+	target(t, []byte{}, true, 5)
+	target(t, []byte{1}, false, 0)
+	assert.Equal(t, []*ctorAccount{{id: "alice", limit: 5}, {id: "bob"}}, called)
+
+	gomock.InOrder(
+		mockF.EXPECT().Fatalf("fuzzing: %v", gomock.Any()).Do(func(format string, args ...any) {
+			assert.EqualError(t, args[0].(error), `no field of type string at field path "Arg2" in struct { Arg0 string; Arg1 *int } to bind a generator to`)
+		}),
+		mockF.EXPECT().Fatalf("fuzzing: the options have the constructor %s, not %s", gomock.Any(), "fuzzing.newCtorAccount"),
+	)
+	c.Add(mockF, []any{"carol", nil}, WithFieldGen("Arg2", Const("x")))
+	other, err := NewConstructor[*ctorAccount](func(id string, limit *int) *ctorAccount { return nil })
+	require.NoError(t, err)
+	c.Add(mockF, []any{"carol", nil}, WithConstructor(other))
+}
+
+func TestFuzz_WithConstructor_WrongType(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	c, err := NewConstructor[*ctorAccount](newCtorAccount)
+	require.NoError(t, err)
+	mockF.EXPECT().Fatalf("fuzzing: %v", gomock.Any()).Do(func(format string, args ...any) {
+		assert.EqualError(t, args[0].(error), "constructor fuzzing.newCtorAccount returns *fuzzing.ctorAccount, not int")
+	})
+	Fuzz(mockF, func(t *testing.T, i int) {}, WithConstructor(c))
+}
+
+func TestAdd_WithConstructor(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	c, err := NewConstructor[*ctorAccount](newCtorAccount)
+	require.NoError(t, err)
+	mockF.EXPECT().Fatalf("fuzzing: %v", gomock.Any()).Do(func(format string, args ...any) {
+		assert.EqualError(t, args[0].(error), "values of *fuzzing.ctorAccount are built by the constructor fuzzing.newCtorAccount, add its arguments with Constructor.Add instead")
+	})
+	Add(mockF, &ctorAccount{id: "alice"}, WithConstructor(c))
+}
+
+func TestDescribeFailingInput_ConstructorCall(t *testing.T) {
+	c, err := NewConstructor[*ctorAccount](newCtorAccount)
+	require.NoError(t, err)
+	args := reflect.New(c.c.args).Elem()
+	args.Field(0).SetString("alice")
+	args.Field(1).Set(reflect.ValueOf(ptr(5)))
+	expected := "decoded arguments of fuzzing.newCtorAccount:" +
+		"\n\tArg0 = \"alice\"" +
+		"\n\tArg1 = &5" +
+		"\nas Go call:" +
+		"\n\tfuzzing.newCtorAccount(\"alice\", ptr(5))" +
+		"\n\t// " + PtrHelper
+	assert.Equal(t, expected, describeFailingInput("", constructorCall{c: c.c, args: args}))
+}
+
+func TestConstruct_Panics(t *testing.T) {
+	c, err := NewConstructor[*ctorAccount](func(id string) *ctorAccount {
+		panic("bad id " + id)
+	})
+	require.NoError(t, err)
+	args := reflect.New(c.c.args).Elem()
+	args.Field(0).SetString("alice")
+
+	r := &fakeReporter{}
+	_, panicked, err := construct(r, "", c.c, args, constructorCall{c: c.c, args: args})
+	assert.True(t, panicked)
+	assert.NoError(t, err)
+	require.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "panicked: bad id alice\ndecoded arguments of ")
+	assert.Contains(t, r.errors[0], "\n\tArg0 = \"alice\"\nas Go call:\n\t")
+
+	r = &fakeReporter{}
+	c, err = NewConstructor[*ctorAccount](newCtorAccount)
+	require.NoError(t, err)
+	value, panicked, err := construct(r, "", c.c, reflect.New(c.c.args).Elem(), nil)
+	assert.False(t, panicked)
+	assert.NoError(t, err)
+	assert.Equal(t, &ctorAccount{}, value.Interface())
+	assert.Empty(t, r.errors)
+}
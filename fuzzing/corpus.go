@@ -0,0 +1,482 @@
+package fuzzing
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// corpusFileHeader is the first line of every file in a Go fuzz corpus.
+const corpusFileHeader = "go test fuzz v1"
+
+// CorpusEntry is a decoded entry of a Go fuzz corpus.
+type CorpusEntry[T any] struct {
+	// Path is the corpus file the entry was read from.
+	Path string
+	// Value is the decoded entry. It is only valid if Err is nil.
+	Value T
+	// Err is set if the file could not be read or does not match the layout
+	// of T.
+	Err error
+}
+
+// CorpusDir returns the directory, relative to the package directory, that
+// `go test` reads the seed corpus of the fuzz test fuzzName from.
+func CorpusDir(fuzzName string) string {
+	return filepath.Join("testdata", "fuzz", fuzzName)
+}
+
+// WriteCorpusFile writes t into the corpus directory dir, such as
+// CorpusDir("FuzzMyFunc"), in the `go test fuzz v1` format. t is flattened
+// the same way as by Add, so pass the options passed to Fuzz. Like the Go
+// fuzzing engine, the file is named after the hash of its content. The path
+// of the written file is returned.
+func WriteCorpusFile[T any](dir string, t T, opts ...Option) (string, error) {
+	fields, err := flatten(t, newConfig(opts))
+	if err != nil {
+		return "", err
+	}
+	data, err := marshalCorpusFile(fields)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, corpusFileName(data))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func corpusFileName(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+}
+
+// marshalCorpusFile encodes vals in the `go test fuzz v1` format, exactly as
+// the Go fuzzing engine does.
+func marshalCorpusFile(vals []any) ([]byte, error) {
+	b := bytes.NewBufferString(corpusFileHeader + "\n")
+	for _, val := range vals {
+		switch t := val.(type) {
+		case int, int8, int16, int64, uint, uint16, uint32, uint64, bool:
+			fmt.Fprintf(b, "%T(%v)\n", t, t)
+		case float32:
+			if math.IsNaN(float64(t)) && math.Float32bits(t) != math.Float32bits(float32(math.NaN())) {
+				fmt.Fprintf(b, "math.Float32frombits(0x%x)\n", math.Float32bits(t))
+			} else {
+				fmt.Fprintf(b, "%T(%v)\n", t, t)
+			}
+		case float64:
+			if math.IsNaN(t) && math.Float64bits(t) != math.Float64bits(math.NaN()) {
+				fmt.Fprintf(b, "math.Float64frombits(0x%x)\n", math.Float64bits(t))
+			} else {
+				fmt.Fprintf(b, "%T(%v)\n", t, t)
+			}
+		case string:
+			fmt.Fprintf(b, "string(%q)\n", t)
+		case rune: // int32
+			if utf8.ValidRune(t) && unicode.IsPrint(t) {
+				fmt.Fprintf(b, "rune(%q)\n", t)
+			} else {
+				fmt.Fprintf(b, "int32(%v)\n", t)
+			}
+		case byte: // uint8
+			fmt.Fprintf(b, "byte(%q)\n", t)
+		case []byte:
+			fmt.Fprintf(b, "[]byte(%q)\n", t)
+		default:
+			return nil, fmt.Errorf("unsupported type %T in a go test fuzz v1 corpus", val)
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// DecodeCorpus decodes the `go test fuzz v1` corpus file at path into values
+// of type T, using the same layout as Fuzz. If path is a directory, such as
+// testdata/fuzz/FuzzMyFunc, every file in it is decoded.
+func DecodeCorpus[T any](path string) ([]CorpusEntry[T], error) {
+	paths, err := corpusFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]CorpusEntry[T], 0, len(paths))
+	for _, p := range paths {
+		entry := CorpusEntry[T]{Path: p}
+		entry.Value, entry.Err = DecodeCorpusFile[T](p)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// DecodeCorpusFile decodes a single `go test fuzz v1` corpus file into a
+// value of type T.
+func DecodeCorpusFile[T any](path string) (T, error) {
+	var t T
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return t, err
+	}
+	vals, err := unmarshalCorpusFile(data)
+	if err != nil {
+		return t, fmt.Errorf("%s: %w", path, err)
+	}
+	t, err = decodeFields[T](vals)
+	if err != nil {
+		return t, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// decodeFields builds a T from flattened fields, as passed to the fuzz target.
+func decodeFields[T any](vals []any) (T, error) {
+	var t T
+	value, err := decodeSlots(reflect.TypeFor[T](), nil, vals)
+	if err != nil {
+		return t, err
+	}
+	return value.Interface().(T), nil
+}
+
+// decodeSlots builds a value of type t from vals, the slots of the layout of
+// t with the generators gens.
+func decodeSlots(t reflect.Type, gens genBindings, vals []any) (reflect.Value, error) {
+	if err := checkSlots(t, gens, vals); err != nil {
+		return reflect.Value{}, err
+	}
+	builder := buildAnyTraverser{
+		fields: slotValues(vals),
+		gens:   gens,
+	}
+	return builder.traverseType(t), nil
+}
+
+// checkSlots returns an error if vals do not match the layout of t with the
+// generators gens.
+func checkSlots(t reflect.Type, gens genBindings, vals []any) error {
+	fieldsTraverser := anyToFieldsTraverser{gens: gens}
+	fieldsTraverser.traverseType(t)
+	if len(vals) != len(fieldsTraverser.fieldsTypes) {
+		return fmt.Errorf("%v has %d fields, got %d values", t, len(fieldsTraverser.fieldsTypes), len(vals))
+	}
+	for i, val := range vals {
+		fieldType := fieldsTraverser.fieldsTypes[i]
+		if reflect.TypeOf(val) != fieldType {
+			return fmt.Errorf("field %d of %v is %v, got %T", i, t, fieldType, val)
+		}
+	}
+	return nil
+}
+
+// canonicalSlots returns vals, which match the layout of t with the
+// generators gens, with zero payloads behind nil pointers.
+func canonicalSlots(t reflect.Type, gens genBindings, vals []any) []any {
+	builder := buildAnyTraverser{
+		fields: slotValues(vals),
+		gens:   gens,
+	}
+	builder.traverseType(t)
+	canonical := append([]any{}, vals...)
+	for _, i := range builder.discardedSlots {
+		if reflect.TypeOf(vals[i]).Kind() == reflect.Slice {
+			canonical[i] = reflect.MakeSlice(reflect.TypeOf(vals[i]), 0, 0).Interface()
+		} else {
+			canonical[i] = reflect.Zero(reflect.TypeOf(vals[i])).Interface()
+		}
+	}
+	return canonical
+}
+
+func slotValues(vals []any) []reflect.Value {
+	fields := make([]reflect.Value, 0, len(vals))
+	for _, val := range vals {
+		fields = append(fields, reflect.ValueOf(val))
+	}
+	return fields
+}
+
+// corpusSlots is a corpus file read as the slots of a layout.
+type corpusSlots struct {
+	path string
+	vals []any
+	// err is set if the file could not be read or does not match the layout.
+	err error
+}
+
+// readCorpus reads the `go test fuzz v1` corpus files at path, a file or a
+// directory, as the slots of the layout of t with the generators gens.
+func readCorpus(path string, t reflect.Type, gens genBindings) ([]corpusSlots, error) {
+	paths, err := corpusFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]corpusSlots, 0, len(paths))
+	for _, p := range paths {
+		entry := corpusSlots{path: p}
+		entry.vals, entry.err = readCorpusSlots(p, t, gens)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// readCorpusSlots reads the `go test fuzz v1` corpus file at path, and checks
+// it against the layout of t with the generators gens.
+func readCorpusSlots(path string, t reflect.Type, gens genBindings) ([]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := unmarshalCorpusFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := checkSlots(t, gens, vals); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return vals, nil
+}
+
+func corpusFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	dirEntries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			continue
+		}
+		paths = append(paths, filepath.Join(path, dirEntry.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// unmarshalCorpusFile parses the `go test fuzz v1` format written by the Go
+// fuzzing engine: a header line, followed by one Go expression per value.
+func unmarshalCorpusFile(data []byte) ([]any, error) {
+	lines := bytes.Split(data, []byte("\n"))
+	if len(lines) == 0 || string(bytes.TrimSpace(lines[0])) != corpusFileHeader {
+		return nil, fmt.Errorf("missing %q header", corpusFileHeader)
+	}
+	vals := []any{}
+	for i, line := range lines[1:] {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		val, err := parseCorpusValue(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+2, err)
+		}
+		vals = append(vals, val)
+	}
+	if len(vals) == 0 {
+		return nil, fmt.Errorf("no values")
+	}
+	return vals, nil
+}
+
+func parseCorpusValue(line []byte) (any, error) {
+	fs := token.NewFileSet()
+	expr, err := parser.ParseExprFrom(fs, "(corpus)", line, 0)
+	if err != nil {
+		return nil, err
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return nil, fmt.Errorf("expected call expression, got %T", expr)
+	}
+	if len(call.Args) != 1 {
+		return nil, fmt.Errorf("expected 1 argument to call, got %d", len(call.Args))
+	}
+	arg := call.Args[0]
+
+	if arrayType, ok := call.Fun.(*ast.ArrayType); ok {
+		if arrayType.Len != nil {
+			return nil, fmt.Errorf("expected []byte, got array type")
+		}
+		if elem, ok := arrayType.Elt.(*ast.Ident); !ok || elem.Name != "byte" {
+			return nil, fmt.Errorf("expected []byte")
+		}
+		lit, ok := arg.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return nil, fmt.Errorf("string literal required for type []byte")
+		}
+		s, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	}
+
+	if selector, ok := call.Fun.(*ast.SelectorExpr); ok {
+		// NaN values that are not math.NaN() are written as their bits.
+		pkg, ok := selector.X.(*ast.Ident)
+		if !ok || pkg.Name != "math" {
+			return nil, fmt.Errorf("unsupported function %v", selector.Sel.Name)
+		}
+		lit, ok := arg.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return nil, fmt.Errorf("integer literal required for %s", selector.Sel.Name)
+		}
+		switch selector.Sel.Name {
+		case "Float64frombits":
+			bits, err := strconv.ParseUint(lit.Value, 0, 64)
+			if err != nil {
+				return nil, err
+			}
+			return math.Float64frombits(bits), nil
+		case "Float32frombits":
+			bits, err := strconv.ParseUint(lit.Value, 0, 32)
+			if err != nil {
+				return nil, err
+			}
+			return math.Float32frombits(uint32(bits)), nil
+		default:
+			return nil, fmt.Errorf("unsupported function math.%s", selector.Sel.Name)
+		}
+	}
+
+	typeIdent, ok := call.Fun.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("expected type conversion")
+	}
+	typeName := typeIdent.Name
+
+	if typeName == "bool" {
+		ident, ok := arg.(*ast.Ident)
+		if !ok {
+			return nil, fmt.Errorf("bool literal required for type bool")
+		}
+		switch ident.Name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		default:
+			return nil, fmt.Errorf("invalid bool %q", ident.Name)
+		}
+	}
+
+	negative := false
+	if unary, ok := arg.(*ast.UnaryExpr); ok {
+		switch unary.Op {
+		case token.SUB:
+			negative = true
+		case token.ADD:
+		default:
+			return nil, fmt.Errorf("unsupported operator %v", unary.Op)
+		}
+		arg = unary.X
+	}
+
+	if ident, ok := arg.(*ast.Ident); ok {
+		// Infinities and NaN are written without a literal.
+		var f float64
+		switch ident.Name {
+		case "Inf":
+			f = math.Inf(1)
+		case "NaN":
+			f = math.NaN()
+		default:
+			return nil, fmt.Errorf("unsupported identifier %q", ident.Name)
+		}
+		if negative {
+			f = -f
+		}
+		switch typeName {
+		case "float32":
+			return float32(f), nil
+		case "float64":
+			return f, nil
+		default:
+			return nil, fmt.Errorf("%s is not a valid %s", ident.Name, typeName)
+		}
+	}
+
+	lit, ok := arg.(*ast.BasicLit)
+	if !ok {
+		return nil, fmt.Errorf("literal value required for type %s", typeName)
+	}
+	value := lit.Value
+	switch lit.Kind {
+	case token.STRING:
+		if typeName != "string" || negative {
+			return nil, fmt.Errorf("string literal is not a valid %s", typeName)
+		}
+		return strconv.Unquote(value)
+	case token.CHAR:
+		r, _, _, err := strconv.UnquoteChar(value[1:len(value)-1], '\'')
+		if err != nil {
+			return nil, err
+		}
+		value = strconv.Itoa(int(r))
+		if negative {
+			return nil, fmt.Errorf("character literal cannot be negative")
+		}
+	}
+	if negative {
+		value = "-" + value
+	}
+	return parseCorpusNumber(typeName, value)
+}
+
+func parseCorpusNumber(typeName string, value string) (any, error) {
+	switch typeName {
+	case "int":
+		i, err := strconv.ParseInt(value, 0, strconv.IntSize)
+		return int(i), err
+	case "int8":
+		i, err := strconv.ParseInt(value, 0, 8)
+		return int8(i), err
+	case "int16":
+		i, err := strconv.ParseInt(value, 0, 16)
+		return int16(i), err
+	case "int32", "rune":
+		i, err := strconv.ParseInt(value, 0, 32)
+		return int32(i), err
+	case "int64":
+		return strconv.ParseInt(value, 0, 64)
+	case "uint":
+		u, err := strconv.ParseUint(value, 0, strconv.IntSize)
+		return uint(u), err
+	case "uint8", "byte":
+		u, err := strconv.ParseUint(value, 0, 8)
+		return uint8(u), err
+	case "uint16":
+		u, err := strconv.ParseUint(value, 0, 16)
+		return uint16(u), err
+	case "uint32":
+		u, err := strconv.ParseUint(value, 0, 32)
+		return uint32(u), err
+	case "uint64":
+		return strconv.ParseUint(value, 0, 64)
+	case "float32":
+		f, err := strconv.ParseFloat(value, 32)
+		return float32(f), err
+	case "float64":
+		return strconv.ParseFloat(value, 64)
+	default:
+		return nil, fmt.Errorf("unsupported type %q", typeName)
+	}
+}
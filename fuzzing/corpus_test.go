@@ -0,0 +1,173 @@
+package fuzzing
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeCorpusFile(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestUnmarshalCorpusFile(t *testing.T) {
+	data := `go test fuzz v1
+string("foo\xff")
+[]byte("bar")
+bool(true)
+int(-42)
+int8(8)
+int16(-16)
+int32(32)
+rune('a')
+int64(64)
+uint(1)
+byte('b')
+uint8(8)
+uint16(16)
+uint32(32)
+uint64(0x40)
+float32(3.14)
+float64(-0)
+float64(+Inf)
+float64(-Inf)
+math.Float64frombits(0x7ff8000000000001)
+`
+	vals, err := unmarshalCorpusFile([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, vals, 20)
+	assert.Equal(t, []any{
+		"foo\xff", []byte("bar"), true, -42, int8(8), int16(-16), int32(32), int32('a'), int64(64),
+		uint(1), uint8('b'), uint8(8), uint16(16), uint32(32), uint64(64), float32(3.14),
+	}, vals[:16])
+	assert.True(t, math.Signbit(vals[16].(float64)))
+	assert.Equal(t, math.Inf(1), vals[17])
+	assert.Equal(t, math.Inf(-1), vals[18])
+	assert.Equal(t, uint64(0x7ff8000000000001), math.Float64bits(vals[19].(float64)))
+}
+
+func TestUnmarshalCorpusFile_Errors(t *testing.T) {
+	for name, data := range map[string]string{
+		"no header":       "string(\"foo\")\n",
+		"no values":       "go test fuzz v1\n",
+		"unknown type":    "go test fuzz v1\ncomplex128(1)\n",
+		"not a call":      "go test fuzz v1\n42\n",
+		"out of range":    "go test fuzz v1\nint8(300)\n",
+		"negative string": "go test fuzz v1\nstring(-\"foo\")\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := unmarshalCorpusFile([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDecodeCorpus(t *testing.T) {
+	type Foo struct {
+		S string
+		P *int
+	}
+	dir := t.TempDir()
+	writeCorpusFile(t, dir, "a", "go test fuzz v1\nstring(\"foo\")\nbool(true)\nint(42)\n")
+	writeCorpusFile(t, dir, "b", "go test fuzz v1\nstring(\"bar\")\nbool(false)\nint(42)\n")
+	writeCorpusFile(t, dir, "c", "go test fuzz v1\nstring(\"baz\")\n")
+	writeCorpusFile(t, dir, "d", "go test fuzz v1\nstring(\"baz\")\nint(1)\nint(42)\n")
+
+	entries, err := DecodeCorpus[Foo](dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	assert.Equal(t, filepath.Join(dir, "a"), entries[0].Path)
+	assert.NoError(t, entries[0].Err)
+	assert.Equal(t, Foo{S: "foo", P: ptr(42)}, entries[0].Value)
+
+	assert.NoError(t, entries[1].Err)
+	assert.Equal(t, Foo{S: "bar"}, entries[1].Value)
+
+	assert.ErrorContains(t, entries[2].Err, "has 3 fields, got 1 values")
+	assert.ErrorContains(t, entries[3].Err, "field 1 of fuzzing.Foo is bool, got int")
+}
+
+func TestDecodeCorpus_File(t *testing.T) {
+	path := writeCorpusFile(t, t.TempDir(), "a", "go test fuzz v1\nint(42)\n")
+	entries, err := DecodeCorpus[int](path)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 42, entries[0].Value)
+}
+
+func TestDecodeCorpus_Missing(t *testing.T) {
+	_, err := DecodeCorpus[int](filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestMarshalCorpusFile_RoundTrip(t *testing.T) {
+	vals := []any{
+		"foo\xff", []byte("bar"), true, -42, int8(8), int16(-16), int32('a'), int32(-1), int64(64),
+		uint(1), uint8('b'), uint8(0), uint16(16), uint32(32), uint64(64), float32(3.14), 1e21,
+		math.Inf(-1), float32(math.NaN()), math.Float64frombits(0x7ff8000000000001),
+	}
+	data, err := marshalCorpusFile(vals)
+	require.NoError(t, err)
+	parsed, err := unmarshalCorpusFile(data)
+	require.NoError(t, err)
+	require.Len(t, parsed, len(vals))
+	assert.Equal(t, vals[:18], parsed[:18])
+	assert.True(t, math.IsNaN(float64(parsed[18].(float32))))
+	assert.Equal(t, uint64(0x7ff8000000000001), math.Float64bits(parsed[19].(float64)))
+}
+
+func TestMarshalCorpusFile_Format(t *testing.T) {
+	data, err := marshalCorpusFile([]any{"foo", true, 42, int32('a'), uint8('b')})
+	require.NoError(t, err)
+	assert.Equal(t, "go test fuzz v1\nstring(\"foo\")\nbool(true)\nint(42)\nrune('a')\nbyte('b')\n", string(data))
+}
+
+func TestMarshalCorpusFile_Unsupported(t *testing.T) {
+	_, err := marshalCorpusFile([]any{complex64(1)})
+	assert.ErrorContains(t, err, "unsupported type complex64")
+}
+
+func TestWriteCorpusFile_WithGen(t *testing.T) {
+	dir := t.TempDir()
+	path, err := WriteCorpusFile(dir, genUser{Name: "bob", Email: "bob@example.com"}, WithFieldGen("Email", genEmail))
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "go test fuzz v1\nstring(\"bob\")\n[]byte(\"\")\nbool(false)\nint(0)\n", string(data))
+
+	_, err = WriteCorpusFile(dir, genUser{}, WithFieldGen("Mail", genEmail))
+	assert.Error(t, err)
+}
+
+func TestWriteCorpusFile(t *testing.T) {
+	type Foo struct {
+		S string
+		P *int
+		N *int
+	}
+	dir := filepath.Join(t.TempDir(), CorpusDir("FuzzFoo"))
+	foo := Foo{S: "foo", P: ptr(42)}
+	path, err := WriteCorpusFile(dir, foo)
+	require.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(path))
+	assert.Len(t, filepath.Base(path), 16)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "go test fuzz v1\nstring(\"foo\")\nbool(true)\nint(42)\nbool(false)\nint(0)\n", string(data))
+
+	decoded, err := DecodeCorpusFile[Foo](path)
+	require.NoError(t, err)
+	assert.Equal(t, foo, decoded)
+
+	// Writing the same value again results in the same file.
+	samePath, err := WriteCorpusFile(dir, foo)
+	require.NoError(t, err)
+	assert.Equal(t, path, samePath)
+}
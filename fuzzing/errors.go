@@ -0,0 +1,65 @@
+package fuzzing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxErrorDepth is how deeply ErrorGen nests wrapped and joined errors.
+const maxErrorDepth = 3
+
+// ErrorGen generates errors for error handling paths: nil, one of sentinels,
+// an error wrapped with fmt.Errorf("...: %w"), or an errors.Join of up to
+// three errors, nested a few levels deep. Wrapped and joined errors are
+// generated the same way, so errors.Is finds the sentinels in them. Without
+// sentinels, errors.New errors with messages from the input are generated
+// instead. Once the input is exhausted, nil is generated.
+func ErrorGen(sentinels ...error) Gen[error] {
+	return func(s *Source) error {
+		return generateError(s, sentinels, 0)
+	}
+}
+
+func generateError(s *Source, sentinels []error, depth int) error {
+	kinds := 4
+	if depth >= maxErrorDepth {
+		// Only nil and leaf errors.
+		kinds = 2
+	}
+	return generateErrorOfKind(s, sentinels, depth, s.IntN(kinds))
+}
+
+// generateWrappedError generates the error wrapped by fmt.Errorf, which is
+// never nil, as fmt.Errorf renders a nil error as %!w(<nil>). A nil error is
+// replaced by a leaf error.
+func generateWrappedError(s *Source, sentinels []error, depth int) error {
+	if err := generateError(s, sentinels, depth); err != nil {
+		return err
+	}
+	return generateErrorOfKind(s, sentinels, depth, 1)
+}
+
+func generateErrorOfKind(s *Source, sentinels []error, depth int, kind int) error {
+	switch kind {
+	case 0:
+		return nil
+	case 1:
+		if len(sentinels) == 0 {
+			return errors.New(s.Text())
+		}
+		return sentinels[s.IntN(len(sentinels))]
+	case 2:
+		return fmt.Errorf("%s: %w", s.Text(), generateWrappedError(s, sentinels, depth+1))
+	default:
+		errs := SliceOf(func(s *Source) error {
+			return generateError(s, sentinels, depth+1)
+		}, 3)(s)
+		return errors.Join(errs...)
+	}
+}
+
+// WithErrors binds ErrorGen(sentinels...) to every field of type error, see
+// WithGen. Pass it to FakeGen too, for fakes to return the generated errors.
+func WithErrors(sentinels ...error) Option {
+	return WithGen(ErrorGen(sentinels...))
+}
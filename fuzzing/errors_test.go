@@ -0,0 +1,101 @@
+package fuzzing
+
+import (
+	"errors"
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"io"
+	"math/rand/v2"
+	"testing"
+)
+
+var errTestNotFound = errors.New("not found")
+
+func TestErrorGen(t *testing.T) {
+	gen := ErrorGen(errTestNotFound, io.EOF)
+
+	assert.NoError(t, gen(NewSource(nil)))
+	assert.NoError(t, gen(NewSource([]byte{0})))
+	assert.Equal(t, io.EOF, gen(NewSource([]byte{1, 1})))
+
+	// Wrapped with a message of 3 bytes.
+	err := gen(NewSource([]byte{2, 3, 'g', 'e', 't', 1, 0}))
+	assert.EqualError(t, err, "get: not found")
+	assert.True(t, errors.Is(err, errTestNotFound))
+
+	// A join of a sentinel and nil.
+	err = gen(NewSource([]byte{3, 1, 1, 1, 1, 0, 0}))
+	assert.EqualError(t, err, "EOF")
+	assert.True(t, errors.Is(err, io.EOF))
+	assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 1)
+}
+
+func TestErrorGen_NoSentinels(t *testing.T) {
+	assert.EqualError(t, ErrorGen()(NewSource([]byte{1, 4, 'o', 'o', 'p', 's'})), "oops")
+}
+
+func TestErrorGen_MaxDepth(t *testing.T) {
+	// Wrap forever, until the depth limit only allows leaves.
+	data := []byte{}
+	for i := 0; i < maxErrorDepth; i++ {
+		data = append(data, 2, 1, 'w')
+	}
+	data = append(data, 3, 1)
+	err := ErrorGen(io.EOF)(NewSource(data))
+	assert.EqualError(t, err, "w: w: w: EOF")
+}
+
+func TestErrorGen_WrapsNonNil(t *testing.T) {
+	// The wrapped error is drawn as nil, and replaced by a leaf.
+	err := ErrorGen(io.EOF)(NewSource([]byte{2, 1, 'w', 0, 0}))
+	assert.EqualError(t, err, "w: EOF")
+
+	// A join of nothing is nil too.
+	err = ErrorGen()(NewSource([]byte{2, 1, 'w', 3, 0, 2, 'o', 'k'}))
+	assert.EqualError(t, err, "w: ok")
+
+	gen := ErrorGen(errTestNotFound)
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 10000; i++ {
+		data := make([]byte, rng.IntN(64))
+		for j := range data {
+			data[j] = byte(rng.IntN(256))
+		}
+		if err := gen(NewSource(data)); err != nil {
+			assert.NotContains(t, err.Error(), "%!w", "input %v", data)
+		}
+	}
+}
+
+type errorsInput struct {
+	Err error
+	N   int
+}
+
+func TestFuzz_WithErrors(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	var target func(*testing.T, []byte, int)
+	mockF.EXPECT().Fuzz(gomock.Any()).Do(func(fuzzTarget any) {
+		target = fuzzTarget.(func(*testing.T, []byte, int))
+	})
+	var errs []error
+	Fuzz(mockF, func(t *testing.T, input errorsInput) {
+		errs = append(errs, input.Err)
+	}, WithErrors(errTestNotFound))
+
+	target(t, []byte{}, 0)
+	target(t, []byte{1, 0}, 0)
+	assert.Equal(t, []error{nil, errTestNotFound}, errs)
+}
+
+func TestFakeGen_WithErrors(t *testing.T) {
+	clock := FakeGen(func(f *Fake) fakeTestClock { return fakeClock{f} }, WithErrors(errTestNotFound))(NewSource([]byte{2, 1, 0}))
+	// The error is generated from the bytes of a []byte slot, {1, 0}.
+	assert.Equal(t, errTestNotFound, clock.Sleep(1))
+	assert.NoError(t, clock.Sleep(2))
+}
@@ -0,0 +1,101 @@
+package fuzzing
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrFake is the error returned by fakes, wrapped with the method and the
+// number of the call, for results of type error that are not nil.
+var ErrFake = errors.New("fuzzing: fake error")
+
+// Fake is a fake implementation of an interface, such as a Clock or a Store,
+// whose results are drawn from the fuzz input. Every call draws its results
+// anew, so the fuzzing engine controls which call returns what: short reads,
+// an error on the third call, or odd values. Results of type error are nil or
+// wrap ErrFake, and results of other types are decoded like the fields of the
+// type passed to Fuzz. Once the input is exhausted, calls return zero values.
+//
+// A Fake is embedded in a small adapter type, which `fuzz-all fake` generates:
+//
+//	type fakeClock struct {
+//		*fuzzing.Fake
+//	}
+//
+//	func (f fakeClock) Now() (r0 time.Time) {
+//		f.Fake.Call("Now", []any{}, &r0)
+//		return
+//	}
+//
+// Inject fakes into fields of the fuzzed type with WithGen and FakeGen.
+//
+// A Fake is safe for concurrent use. Concurrent calls are serialized, and draw
+// their results in the order they get hold of the Fake, which depends on
+// scheduling. So the same input only reproduces the same results if the code
+// under test orders its calls.
+type Fake struct {
+	mu     sync.Mutex
+	source *Source
+	gens   genBindings
+	calls  []MethodCall
+}
+
+// NewFake returns a Fake that draws results from s.
+func NewFake(s *Source) *Fake {
+	return &Fake{source: s}
+}
+
+// FakeGen generates fakes that draw their results from the input of the
+// generator. wrap wraps the Fake in the adapter type that implements I.
+// Generators bound to a type with WithGen in opts generate the results of
+// that type.
+//
+//	fuzzing.WithGen(fuzzing.FakeGen(func(f *fuzzing.Fake) Clock { return fakeClock{f} }))
+func FakeGen[I any](wrap func(f *Fake) I, opts ...Option) Gen[I] {
+	gens := newConfig(opts).gens
+	return func(s *Source) I {
+		return wrap(&Fake{source: s, gens: gens})
+	}
+}
+
+// Call records a call of method with args, and sets results, which must be
+// pointers to the results of the method, to values drawn from the input. The
+// results are recorded too.
+func (f *Fake) Call(method string, args []any, results ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, MethodCall{Method: method, Args: args})
+	call := &f.calls[len(f.calls)-1]
+	for _, result := range results {
+		resultValue := reflect.ValueOf(result)
+		if resultValue.Kind() != reflect.Pointer || resultValue.IsNil() {
+			panic(fmt.Errorf("fuzzing: results of %s must be non-nil pointers, got %T", method, result))
+		}
+		resultValue.Elem().Set(f.draw(method, resultValue.Type().Elem()))
+		call.Results = append(call.Results, resultValue.Elem().Interface())
+	}
+}
+
+// Calls returns the calls made so far, oldest first, with their results.
+func (f *Fake) Calls() []MethodCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]MethodCall{}, f.calls...)
+}
+
+// draw draws a result of type t of a call of method. f.mu must be held.
+func (f *Fake) draw(method string, t reflect.Type) reflect.Value {
+	if t == errorType && f.gens.lookup("", t) == nil {
+		value := reflect.New(t).Elem()
+		if f.source.Bool() {
+			value.Set(reflect.ValueOf(fmt.Errorf("%w: call %d of %s", ErrFake, len(f.calls), method)))
+		}
+		return value
+	}
+	fieldsTraverser := anyToFieldsTraverser{gens: f.gens}
+	fieldsTraverser.traverseType(t)
+	value, _ := decodeSourceValue(t, fieldsTraverser.fieldsTypes, f.source, f.gens)
+	return value
+}
@@ -0,0 +1,139 @@
+package fuzzing
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type fakeTestClock interface {
+	Now() int64
+	Sleep(d int64) error
+}
+
+type fakeClock struct {
+	*Fake
+}
+
+func (f fakeClock) Now() (r0 int64) {
+	f.Call("Now", []any{}, &r0)
+	return
+}
+
+func (f fakeClock) Sleep(d int64) (r0 error) {
+	f.Call("Sleep", []any{d}, &r0)
+	return
+}
+
+func TestFake_Call(t *testing.T) {
+	data := binary.LittleEndian.AppendUint64(nil, 42)
+	data = append(data, 0, 1)
+	clock := fakeClock{NewFake(NewSource(data))}
+
+	assert.Equal(t, int64(42), clock.Now())
+	assert.NoError(t, clock.Sleep(1))
+	err := clock.Sleep(2)
+	assert.True(t, errors.Is(err, ErrFake))
+	assert.EqualError(t, err, "fuzzing: fake error: call 3 of Sleep")
+	// The input is exhausted.
+	assert.Equal(t, int64(0), clock.Now())
+	assert.NoError(t, clock.Sleep(3))
+	assert.Equal(t, []MethodCall{
+		{Method: "Now", Args: []any{}, Results: []any{int64(42)}},
+		{Method: "Sleep", Args: []any{int64(1)}, Results: []any{nil}},
+		{Method: "Sleep", Args: []any{int64(2)}, Results: []any{err}},
+		{Method: "Now", Args: []any{}, Results: []any{int64(0)}},
+		{Method: "Sleep", Args: []any{int64(3)}, Results: []any{nil}},
+	}, clock.Calls())
+}
+
+func TestFake_CallPanics(t *testing.T) {
+	clock := fakeClock{NewFake(NewSource(nil))}
+	var now int64
+	assert.PanicsWithError(t, "fuzzing: results of Now must be non-nil pointers, got int64", func() {
+		clock.Call("Now", nil, now)
+	})
+}
+
+type fakeUser struct {
+	Clock fakeTestClock
+	N     int
+}
+
+func TestFake_Concurrent(t *testing.T) {
+	data := make([]byte, 8*100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	clock := fakeClock{NewFake(NewSource(data))}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				clock.Now()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, clock.Calls(), 100)
+}
+
+func TestFuzz_FakeGen(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	errSlow := errors.New("slow")
+	opts := []Option{
+		WithGen(FakeGen(func(f *Fake) fakeTestClock { return fakeClock{f} }, WithGen(Const(errSlow)))),
+	}
+	var target func(*testing.T, []byte, int)
+	mockF.EXPECT().Fuzz(gomock.Any()).Do(func(fuzzTarget any) {
+		target = fuzzTarget.(func(*testing.T, []byte, int))
+	})
+	var nows []int64
+	var errs []error
+	Fuzz(mockF, func(t *testing.T, user fakeUser) {
+		nows = append(nows, user.Clock.Now())
+		errs = append(errs, user.Clock.Sleep(int64(user.N)))
+	}, opts...)
+
+	target(t, binary.LittleEndian.AppendUint64(nil, 7), 1)
+	assert.Equal(t, []int64{7}, nows)
+	assert.Equal(t, []error{errSlow}, errs)
+}
+
+type fakeReportInput struct {
+	Clock fakeTestClock
+	N     int
+}
+
+func TestDescribeFailingInput_Fake(t *testing.T) {
+	gens := newConfig([]Option{WithGen(FakeGen(func(f *Fake) fakeTestClock { return fakeClock{f} }))}).gens
+	fields := []reflect.Value{reflect.ValueOf(binary.LittleEndian.AppendUint64(nil, 42)), reflect.ValueOf(7)}
+	builder := buildAnyTraverser{fields: fields, gens: gens}
+	input := builder.traverseType(reflect.TypeFor[fakeReportInput]()).Interface().(fakeReportInput)
+	input.Clock.Now()
+	input.Clock.Sleep(3)
+
+	literalBuilder := buildAnyTraverser{fields: fields, gens: gens, zeroGenerated: true}
+	reported := literalBuilder.traverseType(reflect.TypeFor[fakeReportInput]()).Interface()
+	assert.Equal(t, fakeReportInput{N: 7}, reported)
+	assert.Empty(t, literalBuilder.generated)
+
+	description := describeFailingInput("", generatedInput{input: reported, fields: builder.generated})
+	assert.Equal(t, "decoded input of type fuzzing.fakeReportInput:\n\tClock = nil\n\tN = 7\n"+
+		"as Go literal:\n\tfakeReportInput{N: 7}\n"+
+		"generated fields, zero above, by the input of their generators:\n"+
+		"\tClock = []byte(\"*\\x00\\x00\\x00\\x00\\x00\\x00\\x00\")\n"+
+		"\t\tNow() returned int64(42)\n"+
+		"\t\tSleep(int64(3)) returned nil", description)
+}
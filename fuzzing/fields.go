@@ -0,0 +1,308 @@
+package fuzzing
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// fieldsFileHeader is the first line of a file in the field path keyed format.
+const fieldsFileHeader = "go-fuzz-all fields v1"
+
+// fieldsFileExt is the extension of files in the field path keyed format.
+const fieldsFileExt = ".fields"
+
+// Literals of pointers in the field path keyed format.
+const (
+	nilPointerLiteral = "nil"
+	setPointerLiteral = "&{}"
+)
+
+// WriteFieldsFile writes t into the seed directory dir, such as
+// SeedDir("FuzzMyFunc"), in a format keyed by field path rather than by
+// position, so that it survives reordering of struct fields:
+//
+//	go-fuzz-all fields v1
+//	S = "foo"
+//	*P = 42
+//	N = nil
+//
+// Paths are those of LayoutOf. Pointers that are set are implied by the
+// fields behind them. Pass the options passed to Fuzz: fields bound to
+// generators are left out, as Fuzz does not decode them. The file is named
+// after the hash of its content, and its path is returned.
+func WriteFieldsFile[T any](dir string, t T, opts ...Option) (string, error) {
+	cfg := newConfig(opts)
+	if err := cfg.checkSeedType(reflect.TypeFor[T]()); err != nil {
+		return "", err
+	}
+	return writeFieldsFile(dir, reflect.ValueOf(t), cfg.gens)
+}
+
+func writeFieldsFile(dir string, value reflect.Value, gens genBindings) (string, error) {
+	data := marshalFieldsFile(value, gens)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, corpusFileName(data)+fieldsFileExt)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ReadFieldsFile reads a file written by WriteFieldsFile. Fields that are not
+// in the file are zero, and pointers are nil unless a field behind them is in
+// the file. Paths that are not part of T are an error.
+func ReadFieldsFile[T any](path string) (T, error) {
+	var t T
+	value, err := readFieldsFile(path, reflect.TypeFor[T]())
+	if err != nil {
+		return t, err
+	}
+	return value.Interface().(T), nil
+}
+
+func readFieldsFile(path string, t reflect.Type) (reflect.Value, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	assignments, err := unmarshalFieldsFile(data)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("%s: %w", path, err)
+	}
+	reader := fieldsReader{assignments: assignments, used: map[string]bool{}}
+	value, _, err := reader.read("", t)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("%s: %w", path, err)
+	}
+	for fieldPath := range assignments {
+		if !reader.used[fieldPath] {
+			return reflect.Value{}, fmt.Errorf("%s: %s is not a field of %v", path, displayFieldPath(fieldPath), t)
+		}
+	}
+	return value, nil
+}
+
+// addFieldsSeeds adds every *.fields file in dir to the corpus.
+func addFieldsSeeds(f TestingF, dir string, t reflect.Type, gens genBindings) error {
+	paths, err := seedFiles(dir, fieldsFileExt)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		value, err := readFieldsFile(path, t)
+		if err != nil {
+			return err
+		}
+		fieldsTraverser := anyToFieldsTraverser{gens: gens}
+		fieldsTraverser.traverseValue(value)
+		addFields(f, path, fieldsTraverser.fields)
+	}
+	return nil
+}
+
+func marshalFieldsFile(value reflect.Value, gens genBindings) []byte {
+	b := bytes.NewBufferString(fieldsFileHeader + "\n")
+	writer := fieldsWriter{b: b, gens: gens}
+	writer.write("", value)
+	return b.Bytes()
+}
+
+type fieldsWriter struct {
+	b *bytes.Buffer
+	// gens are the generators bound to fields, which are not written.
+	gens genBindings
+}
+
+// write writes the fields of value at path, and reports whether it wrote any.
+func (w *fieldsWriter) write(path string, value reflect.Value) bool {
+	if w.gens.lookup(path, value.Type()) != nil {
+		return false
+	}
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			fmt.Fprintf(w.b, "%s = %s\n", displayFieldPath(path), nilPointerLiteral)
+			return true
+		}
+		if !w.write(pointeeFieldPath(path, value.Type()), value.Elem()) {
+			fmt.Fprintf(w.b, "%s = %s\n", displayFieldPath(path), setPointerLiteral)
+		}
+		return true
+	case reflect.Struct:
+		wrote := false
+		for i := 0; i < value.NumField(); i++ {
+			structField := value.Type().Field(i)
+			if !structField.IsExported() {
+				continue
+			}
+			if w.write(joinFieldPath(path, structField.Name), value.Field(i)) {
+				wrote = true
+			}
+		}
+		return wrote
+	case reflect.Array, reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Slice, reflect.UnsafePointer:
+		// Not flattened, so there is nothing to write.
+		return false
+	default:
+		fmt.Fprintf(w.b, "%s = %s\n", displayFieldPath(path), formatFieldLiteral(value))
+		return true
+	}
+}
+
+func formatFieldLiteral(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.String:
+		return strconv.Quote(value.String())
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'g', -1, value.Type().Bits())
+	case reflect.Complex64, reflect.Complex128:
+		return strconv.FormatComplex(value.Complex(), 'g', -1, value.Type().Bits())
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+func unmarshalFieldsFile(data []byte) (map[string]string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 64*1024*1024)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != fieldsFileHeader {
+		return nil, fmt.Errorf("missing %q header", fieldsFileHeader)
+	}
+	assignments := map[string]string{}
+	for line := 2; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "//") {
+			continue
+		}
+		fieldPath, literal, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected <path> = <value>", line)
+		}
+		fieldPath = strings.TrimSpace(fieldPath)
+		if fieldPath == displayFieldPath("") {
+			fieldPath = ""
+		}
+		if _, ok := assignments[fieldPath]; ok {
+			return nil, fmt.Errorf("line %d: %s is set more than once", line, displayFieldPath(fieldPath))
+		}
+		assignments[fieldPath] = strings.TrimSpace(literal)
+	}
+	return assignments, scanner.Err()
+}
+
+type fieldsReader struct {
+	assignments map[string]string
+	used        map[string]bool
+}
+
+// read builds a value of type t at path from the assignments, and reports
+// whether any assignment was used for it.
+func (r *fieldsReader) read(path string, t reflect.Type) (reflect.Value, bool, error) {
+	value := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Pointer:
+		literal, assigned := r.assignments[path]
+		if assigned {
+			r.used[path] = true
+			if literal != nilPointerLiteral && literal != setPointerLiteral {
+				return value, true, fmt.Errorf("%s is a pointer, expected %s or %s, got %s",
+					displayFieldPath(path), nilPointerLiteral, setPointerLiteral, literal)
+			}
+		}
+		pointee, used, err := r.read(pointeeFieldPath(path, t), t.Elem())
+		if err != nil {
+			return value, true, err
+		}
+		if assigned && literal == nilPointerLiteral {
+			if used {
+				return value, true, fmt.Errorf("%s is nil, but fields behind it are set", displayFieldPath(path))
+			}
+			return value, true, nil
+		}
+		if assigned || used {
+			value.Set(reflect.New(t.Elem()))
+			value.Elem().Set(pointee)
+		}
+		return value, assigned || used, nil
+	case reflect.Struct:
+		anyUsed := false
+		for i := 0; i < t.NumField(); i++ {
+			structField := t.Field(i)
+			if !structField.IsExported() {
+				continue
+			}
+			fieldValue, used, err := r.read(joinFieldPath(path, structField.Name), structField.Type)
+			if err != nil {
+				return value, true, err
+			}
+			value.Field(i).Set(fieldValue)
+			anyUsed = anyUsed || used
+		}
+		return value, anyUsed, nil
+	case reflect.Array, reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Slice, reflect.UnsafePointer:
+		return value, false, nil
+	default:
+		literal, ok := r.assignments[path]
+		if !ok {
+			return value, false, nil
+		}
+		r.used[path] = true
+		if err := parseFieldLiteral(value, literal); err != nil {
+			return value, true, fmt.Errorf("%s: %w", displayFieldPath(path), err)
+		}
+		return value, true, nil
+	}
+}
+
+func parseFieldLiteral(value reflect.Value, literal string) error {
+	t := value.Type()
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(literal)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(literal, 0, t.Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(literal, 0, t.Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(literal, t.Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		c, err := strconv.ParseComplex(literal, t.Bits())
+		if err != nil {
+			return err
+		}
+		value.SetComplex(c)
+	case reflect.String:
+		s, err := strconv.Unquote(literal)
+		if err != nil {
+			return fmt.Errorf("invalid string literal %s", literal)
+		}
+		value.SetString(s)
+	default:
+		return fmt.Errorf("unsupported kind %v", t.Kind())
+	}
+	return nil
+}
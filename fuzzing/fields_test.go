@@ -0,0 +1,163 @@
+package fuzzing
+
+import (
+	"bytes"
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fieldsNested struct {
+	F *float32
+	C complex64
+}
+
+type fieldsEmpty struct {
+	i int
+}
+
+type fieldsFoo struct {
+	S string
+	B bool
+	P *int
+	N *fieldsNested
+	M *fieldsNested
+	E *fieldsEmpty
+	U uint8
+	F float64
+}
+
+func TestMarshalFieldsFile(t *testing.T) {
+	foo := fieldsFoo{
+		S: "foo\xff",
+		P: ptr(42),
+		N: &fieldsNested{C: complex(1, 2)},
+		E: &fieldsEmpty{},
+		F: math.Inf(-1),
+	}
+	expected := `go-fuzz-all fields v1
+S = "foo\xff"
+B = false
+*P = 42
+N.F = nil
+N.C = (1+2i)
+M = nil
+E = &{}
+U = 0
+F = -Inf
+`
+	assert.Equal(t, expected, string(marshalFieldsFile(reflect.ValueOf(foo), nil)))
+}
+
+func TestWriteFieldsFile_RoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), SeedDir("FuzzFoo"))
+	for _, foo := range []fieldsFoo{
+		{},
+		{S: "foo", B: true, P: ptr(-1), U: math.MaxUint8, F: math.Copysign(0, -1)},
+		{N: &fieldsNested{F: ptr(float32(3.14))}, M: &fieldsNested{}, E: &fieldsEmpty{}},
+	} {
+		path, err := WriteFieldsFile(dir, foo)
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(path, ".fields"))
+		read, err := ReadFieldsFile[fieldsFoo](path)
+		require.NoError(t, err)
+		assert.Equal(t, foo, read)
+		assert.Equal(t, math.Signbit(foo.F), math.Signbit(read.F))
+	}
+}
+
+func TestWriteFieldsFile_WithGen(t *testing.T) {
+	dir := t.TempDir()
+	path, err := WriteFieldsFile(dir, genUser{Name: "bob", Email: "bob@example.com", Age: ptr(3)}, WithFieldGen("Email", genEmail))
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// Email is generated rather than decoded, so it is left out.
+	assert.Equal(t, "go-fuzz-all fields v1\nName = \"bob\"\n*Age = 3\n", string(data))
+
+	_, err = WriteFieldsFile(dir, genUser{}, WithFieldGen("Mail", genEmail))
+	assert.Error(t, err)
+}
+
+func TestReadFieldsFile_ReorderedAndSparse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.fields")
+	content := `go-fuzz-all fields v1
+// Only some fields, in any order. Pointers are set by the fields behind them.
+*N.F = 1.5
+U = 7
+S = "foo"
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	read, err := ReadFieldsFile[fieldsFoo](path)
+	require.NoError(t, err)
+	assert.Equal(t, fieldsFoo{S: "foo", U: 7, N: &fieldsNested{F: ptr(float32(1.5))}}, read)
+}
+
+func TestReadFieldsFile_Errors(t *testing.T) {
+	for name, content := range map[string]string{
+		"no header":         "S = \"foo\"\n",
+		"no assignment":     "go-fuzz-all fields v1\nS\n",
+		"unknown field":     "go-fuzz-all fields v1\nTypo = 1\n",
+		"set twice":         "go-fuzz-all fields v1\nU = 1\nU = 2\n",
+		"out of range":      "go-fuzz-all fields v1\nU = 256\n",
+		"not a string":      "go-fuzz-all fields v1\nS = foo\n",
+		"pointer literal":   "go-fuzz-all fields v1\nP = 1\n",
+		"nil with payload":  "go-fuzz-all fields v1\nP = nil\n*P = 1\n",
+		"unexported fields": "go-fuzz-all fields v1\nE.i = 1\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "a.fields")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			_, err := ReadFieldsFile[fieldsFoo](path)
+			assert.ErrorContains(t, err, path)
+		})
+	}
+}
+
+func TestReadFieldsFile_Root(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.fields")
+	require.NoError(t, os.WriteFile(path, []byte("go-fuzz-all fields v1\n<root> = 42\n"), 0o644))
+	read, err := ReadFieldsFile[int](path)
+	require.NoError(t, err)
+	assert.Equal(t, 42, read)
+}
+
+func TestAddFieldsSeeds(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	dir := t.TempDir()
+	path, err := WriteFieldsFile(dir, recordedFoo{S: "foo", P: ptr(42)})
+	require.NoError(t, err)
+
+	mockF.EXPECT().Add("foo", true, 42)
+	require.NoError(t, addFieldsSeeds(mockF, dir, reflect.TypeFor[recordedFoo](), nil))
+	assert.Equal(t, map[int]string{0: path}, takeSeedNames(mockF).names)
+}
+
+func TestRunTool_Export(t *testing.T) {
+	corpusDir := t.TempDir()
+	seedDir := t.TempDir()
+	_, err := WriteCorpusFile(corpusDir, toolFoo{S: "foo", P: ptr(42)})
+	require.NoError(t, err)
+	writeCorpusFile(t, corpusDir, "bad", "go test fuzz v1\nint(1)\n")
+
+	out := &bytes.Buffer{}
+	require.NoError(t, runTool[toolFoo](ToolRequest{Command: "export", Paths: []string{corpusDir}, Dir: seedDir}, out))
+	assert.Contains(t, out.String(), "skipped "+filepath.Join(corpusDir, "bad"))
+
+	paths, err := seedFiles(seedDir, ".fields")
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	read, err := ReadFieldsFile[toolFoo](paths[0])
+	require.NoError(t, err)
+	assert.Equal(t, toolFoo{S: "foo", P: ptr(42)}, read)
+}
@@ -6,14 +6,45 @@ import (
 	"testing"
 )
 
-func Fuzz[T any](f TestingF, fn func(*testing.T, T)) {
+func Fuzz[T any](f TestingF, fn func(*testing.T, T), opts ...Option) {
+	cfg := newConfig(opts)
+	stats := &rejectionStats{}
 	tType := reflect.TypeFor[T]()
+	// decodeType is the type decoded from the input: T, or the arguments of
+	// the constructor of T.
+	decodeType, err := cfg.decodeType(tType)
+	if err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	if err := checkLayoutSidecar(CorpusDir(f.Name()), layoutOf(decodeType, cfg.gens), isFuzzing(), cfg.layoutKind()); err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	if err := addJSONSeeds(f, SeedDir(f.Name()), decodeType, cfg.gens); err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	if err := addFieldsSeeds(f, SeedDir(f.Name()), decodeType, cfg.gens); err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	if cfg.boundarySeeds {
+		addBoundarySeeds(f, decodeType, cfg.gens)
+	}
 	in := []reflect.Type{
 		reflect.TypeFor[*testing.T](),
 	}
-	fieldsTraverser := anyToFieldsTraverser{}
-	fieldsTraverser.traverseType(tType)
+	fieldsTraverser := anyToFieldsTraverser{gens: cfg.gens}
+	fieldsTraverser.traverseType(decodeType)
 	in = append(in, fieldsTraverser.fieldsTypes...)
+	if err := checkSeedSlots(f, fieldsTraverser.fieldsTypes); err != nil {
+		takeSeedNames(f)
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	seedNames := takeSeedNames(f)
+	skipNonCanonical := isFuzzing() && !cfg.allowNonCanonical
 
 	out := []reflect.Type{}
 
@@ -23,25 +54,144 @@ func Fuzz[T any](f TestingF, fn func(*testing.T, T)) {
 		testingT := args[0].Interface().(*testing.T)
 		builder := buildAnyTraverser{
 			fields: args[1:],
+			gens:   cfg.gens,
+		}
+		decoded := builder.traverseType(decodeType)
+		if skipNonCanonical && builder.nonCanonical {
+			// Mutating the payload of a nil pointer does not change the value,
+			// bail early so the input does not look interesting to the engine.
+			testingT.Skip("non-canonical input")
+		}
+		if builder.rejected {
+			stats.record(true)
+			testingT.Skip("input rejected by generator")
 		}
-		fn(testingT, builder.traverseType(tType).Interface().(T))
+		reported := reportedValue(decodeType, args[1:], cfg.gens, decoded, builder.generated)
+		var input any = reported.Interface()
+		if cfg.constructor != nil {
+			input = constructorCall{c: cfg.constructor, args: reported}
+		}
+		if len(builder.generated) > 0 {
+			input = generatedInput{input: input, fields: builder.generated}
+		}
+		name := seedName(seedNames, testingT.Name(), args[1:])
+		if cfg.constructor != nil {
+			constructed, panicked, err := construct(testingT, name, cfg.constructor, decoded, input)
+			if panicked {
+				testingT.FailNow()
+			}
+			if err != nil {
+				stats.record(true)
+				testingT.Skipf("input rejected by constructor: %v", err)
+			}
+			decoded = constructed
+		}
+		runTarget(testingT, cfg, stats, name, decoded.Interface().(T), input, fn)
 		return nil
 	})
 	f.Fuzz(fuzzTargetValue.Interface())
 }
 
+// reportedValue returns decoded, decoded from slots, as reported on failure:
+// with the fields built by generators zero, as their values are rarely
+// printable. They are reported by the input of their generators instead.
+func reportedValue(t reflect.Type, slots []reflect.Value, gens genBindings, decoded reflect.Value, generated []generatedField) reflect.Value {
+	if len(generated) == 0 {
+		return decoded
+	}
+	literalBuilder := buildAnyTraverser{
+		fields:        slots,
+		gens:          gens,
+		zeroGenerated: true,
+	}
+	return literalBuilder.traverseType(t)
+}
+
+// runTarget runs fn with value, and reports failures with input, which is
+// either value, or the constructor call that built it.
+func runTarget[T any](t *testing.T, cfg *config, stats *rejectionStats, seedName string, value T, input any, fn func(*testing.T, T)) {
+	if !cfg.accepts(value) {
+		stats.record(true)
+		t.Skip("input rejected by precondition")
+	}
+	defer func() {
+		reportFailure(t, recover(), seedName, input)
+		stats.record(t.Skipped())
+	}()
+	fn(t, value)
+}
+
 type buildAnyTraverser struct {
 	fields []reflect.Value
 	value  reflect.Value
+	// nonCanonical is set if a nil pointer was decoded from a non-zero payload.
+	nonCanonical bool
+	// path is the field path of the value currently being built.
+	path string
+	// gens are the generators bound to fields, which take a []byte each.
+	gens genBindings
+	// rejected is set if a generator rejected its input.
+	rejected bool
+	// discarding is the number of nil pointers whose payload is being
+	// decoded. Such payloads are thrown away.
+	discarding int
+	// popped is the number of slots decoded so far.
+	popped int
+	// discardedSlots are the indexes of the slots of thrown away payloads.
+	discardedSlots []int
+	// generated are the fields built by generators.
+	generated []generatedField
+	// zeroGenerated makes fields bound to generators zero, instead of running
+	// the generators.
+	zeroGenerated bool
+}
+
+// generatedField is a field built by a generator, as reported on failure.
+type generatedField struct {
+	path string
+	// data is the input of the generator.
+	data  []byte
+	value reflect.Value
 }
 
 func (a *buildAnyTraverser) popValue() reflect.Value {
 	value := a.fields[0]
 	a.fields = a.fields[1:]
+	a.popped++
+	if a.discarding > 0 {
+		a.discardedSlots = append(a.discardedSlots, a.popped-1)
+	}
+	if a.discarding > 0 && !isZeroSlot(value) {
+		// The payload is thrown away, so anything but zero is a non-canonical
+		// encoding of the same value.
+		a.nonCanonical = true
+	}
 	return value
 }
 
+// isZeroSlot reports whether value, a layout slot, is zero. The []byte slots
+// of generators are zero when empty.
+func isZeroSlot(value reflect.Value) bool {
+	if value.Kind() == reflect.Slice {
+		return value.Len() == 0
+	}
+	return isZeroValue(value)
+}
+
 func (a *buildAnyTraverser) traverseType(t reflect.Type) reflect.Value {
+	if gen := a.gens.lookup(a.path, t); gen != nil {
+		data := a.popValue().Bytes()
+		if a.discarding > 0 || a.zeroGenerated {
+			// Generators are judged by their bytes, not by what they
+			// generate, as they may generate non-zero values from no bytes.
+			return reflect.Zero(t)
+		}
+		source := NewSource(data)
+		value := gen(source)
+		a.rejected = a.rejected || source.Rejected()
+		a.generated = append(a.generated, generatedField{path: a.path, data: data, value: value})
+		return value
+	}
 	switch t.Kind() {
 	case reflect.Bool:
 		fallthrough
@@ -101,7 +251,16 @@ func (a *buildAnyTraverser) traverseType(t reflect.Type) reflect.Value {
 		isSet := a.popValue().Bool()
 		// TODO check this logic
 		pointedToType := t.Elem()
+		parent := a.path
+		a.path = pointeeFieldPath(parent, t)
+		if !isSet {
+			a.discarding++
+		}
 		valueToSet := a.traverseType(pointedToType)
+		if !isSet {
+			a.discarding--
+		}
+		a.path = parent
 		if isSet {
 			valueToSetPtrValue := reflect.New(pointedToType)
 			valueToSetPtrValue.Elem().Set(valueToSet)
@@ -125,7 +284,10 @@ func (a *buildAnyTraverser) traverseType(t reflect.Type) reflect.Value {
 			if !structField.IsExported() {
 				continue
 			}
+			parent := a.path
+			a.path = joinFieldPath(parent, structField.Name)
 			fieldValue := a.traverseType(structField.Type)
+			a.path = parent
 			structValue.Field(i).Set(fieldValue)
 		}
 		return structValue
@@ -12,6 +12,7 @@ func TestFuzz2Struct_NoNesting_NoPtrs(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
 
 	type Foo struct {
 		S string
@@ -27,6 +28,7 @@ func TestFuzz2Struct_NoNesting_Ptrs(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
 
 	type Foo struct {
 		S *string
@@ -150,6 +152,7 @@ func TestBuildAnyTraverser_NoNesting_PtsWithNil(t *testing.T) {
 	builtFoo := builder.traverseType(reflect.TypeFor[Foo]()).Interface().(Foo)
 	expected := Foo{}
 	assert.Equal(t, expected, builtFoo)
+	assert.True(t, builder.nonCanonical)
 }
 
 func TestBuildAnyTraverser_Nesting(t *testing.T) {
@@ -187,6 +190,7 @@ func TestBuildAnyTraverser_Nesting(t *testing.T) {
 		C: ptr(fieldsAny[12].(complex64)),
 	}}
 	assert.Equal(t, expected, builtFoo)
+	assert.False(t, builder.nonCanonical)
 }
 
 func TestBuildAnyTraverser_Unexported(t *testing.T) {
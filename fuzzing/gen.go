@@ -0,0 +1,263 @@
+package fuzzing
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Gen generates values of T from the primitives it decodes from a Source.
+// Because generators consume the fuzz input, the fuzzing engine drives them
+// like any other field, and coverage guidance still works. Any function with
+// the right signature is a generator:
+//
+//	email := fuzzing.Gen[string](func(s *fuzzing.Source) string {
+//		return localPart(s) + "@" + domain(s)
+//	})
+//
+// Bind generators to fields with WithFieldGen and WithGen.
+type Gen[T any] func(s *Source) T
+
+// Const generates v, without consuming any input.
+func Const[T any](v T) Gen[T] {
+	return func(s *Source) T {
+		return v
+	}
+}
+
+// Any generates values of T the same way as Fuzz decodes them.
+func Any[T any]() Gen[T] {
+	fieldsTraverser := anyToFieldsTraverser{}
+	fieldsTraverser.traverseType(reflect.TypeFor[T]())
+	slotTypes := fieldsTraverser.fieldsTypes
+	return func(s *Source) T {
+		value, _ := decodeSource[T](slotTypes, s, nil)
+		return value
+	}
+}
+
+// IntRange generates ints between min and max, inclusive.
+func IntRange(min, max int) Gen[int] {
+	if min > max {
+		panic(fmt.Errorf("fuzzing: IntRange(%d, %d) is empty", min, max))
+	}
+	return func(s *Source) int {
+		return min + int(s.uintN(uint64(max-min)+1))
+	}
+}
+
+// Map generates values of g, transformed by fn.
+func Map[T any, U any](g Gen[T], fn func(T) U) Gen[U] {
+	return func(s *Source) U {
+		return fn(g(s))
+	}
+}
+
+// filterAttempts is the number of values Filter draws before it rejects the
+// input.
+const filterAttempts = 3
+
+// Filter generates values of g for which keep returns true. If a few values
+// in a row are not kept, the input is rejected, and Fuzz skips it like an
+// input rejected by a precondition. Filters that keep few values waste most of
+// the fuzzing effort, so prefer generating valid values directly.
+func Filter[T any](g Gen[T], keep func(T) bool) Gen[T] {
+	return func(s *Source) T {
+		var v T
+		for attempt := 0; attempt < filterAttempts; attempt++ {
+			v = g(s)
+			if keep(v) {
+				return v
+			}
+		}
+		s.Reject()
+		return v
+	}
+}
+
+// OneOf generates values of one of gens, chosen by the input.
+func OneOf[T any](gens ...Gen[T]) Gen[T] {
+	if len(gens) == 0 {
+		panic(fmt.Errorf("fuzzing: OneOf needs at least one generator"))
+	}
+	return func(s *Source) T {
+		return gens[s.IntN(len(gens))](s)
+	}
+}
+
+// WeightedGen is a generator with a weight, for Weighted.
+type WeightedGen[T any] struct {
+	Weight int
+	Gen    Gen[T]
+}
+
+// Weighted generates values of one of choices, chosen by the input. Choices
+// are picked in proportion to their weight for random inputs, which is a hint
+// to the fuzzing engine rather than a guarantee.
+func Weighted[T any](choices ...WeightedGen[T]) Gen[T] {
+	total := 0
+	for _, choice := range choices {
+		if choice.Weight <= 0 {
+			panic(fmt.Errorf("fuzzing: Weighted needs positive weights, got %d", choice.Weight))
+		}
+		total += choice.Weight
+	}
+	if total == 0 {
+		panic(fmt.Errorf("fuzzing: Weighted needs at least one generator"))
+	}
+	return func(s *Source) T {
+		n := s.IntN(total)
+		for _, choice := range choices {
+			if n < choice.Weight {
+				return choice.Gen(s)
+			}
+			n -= choice.Weight
+		}
+		panic("unreachable")
+	}
+}
+
+// SliceOf generates slices of up to maxLen values of g. Every element is
+// preceded by a bool that tells whether there is one more element, so that
+// the fuzzing engine can grow and shrink the slice.
+func SliceOf[T any](g Gen[T], maxLen int) Gen[[]T] {
+	return func(s *Source) []T {
+		slice := []T{}
+		for len(slice) < maxLen && s.Bool() {
+			slice = append(slice, g(s))
+		}
+		return slice
+	}
+}
+
+// MapOf generates maps of up to maxLen entries, with keys of k and values of
+// v. Entries are encoded like the elements of SliceOf, and later entries with
+// the same key replace earlier ones.
+func MapOf[K comparable, V any](k Gen[K], v Gen[V], maxLen int) Gen[map[K]V] {
+	return func(s *Source) map[K]V {
+		m := map[K]V{}
+		for i := 0; i < maxLen && s.Bool(); i++ {
+			key := k(s)
+			m[key] = v(s)
+		}
+		return m
+	}
+}
+
+// Ptr generates nil, or a pointer to a value of g, as chosen by the input.
+func Ptr[T any](g Gen[T]) Gen[*T] {
+	return func(s *Source) *T {
+		if !s.Bool() {
+			return nil
+		}
+		v := g(s)
+		return &v
+	}
+}
+
+// genBinding binds a generator to the values of a type, or to the value at a
+// field path.
+type genBinding struct {
+	t reflect.Type
+	// path is the field path the generator is bound to, unless anyPath is set.
+	path    string
+	anyPath bool
+	gen     func(*Source) reflect.Value
+}
+
+// genBindings are the generators bound by the options of Fuzz. Field path
+// bindings take precedence over type bindings.
+type genBindings []genBinding
+
+// lookup returns the generator bound to the value of type t at field path
+// path, or nil.
+func (g genBindings) lookup(path string, t reflect.Type) func(*Source) reflect.Value {
+	var byType func(*Source) reflect.Value
+	for _, binding := range g {
+		if binding.t != t {
+			continue
+		}
+		if !binding.anyPath && binding.path == path {
+			return binding.gen
+		}
+		if binding.anyPath && byType == nil {
+			byType = binding.gen
+		}
+	}
+	return byType
+}
+
+// check returns an error if a generator can never be used for values of t.
+func (g genBindings) check(t reflect.Type) error {
+	paths := map[string][]reflect.Type{}
+	genPaths(t, "", map[reflect.Type]bool{}, paths)
+	for _, binding := range g {
+		found := false
+		for path, types := range paths {
+			for _, pathType := range types {
+				found = found || pathType == binding.t && (binding.anyPath || path == binding.path)
+			}
+		}
+		if found {
+			continue
+		}
+		if binding.anyPath {
+			return fmt.Errorf("no field of type %v in %v to bind a generator to", binding.t, t)
+		}
+		return fmt.Errorf("no field of type %v at field path %q in %v to bind a generator to", binding.t, binding.path, t)
+	}
+	return nil
+}
+
+// genPaths adds the field path and type of every value in a value of type t,
+// at field path path, that a generator can be bound to, to paths.
+func genPaths(t reflect.Type, path string, onStack map[reflect.Type]bool, paths map[string][]reflect.Type) {
+	paths[path] = append(paths[path], t)
+	if onStack[t] {
+		// Stop at recursive types.
+		return
+	}
+	onStack[t] = true
+	defer delete(onStack, t)
+	switch t.Kind() {
+	case reflect.Pointer:
+		genPaths(t.Elem(), pointeeFieldPath(path, t), onStack, paths)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				genPaths(t.Field(i).Type, joinFieldPath(path, t.Field(i).Name), onStack, paths)
+			}
+		}
+	}
+}
+
+func bindGen[V any](g Gen[V]) func(*Source) reflect.Value {
+	return func(s *Source) reflect.Value {
+		// Go through a pointer to keep the static type of interfaces.
+		v := g(s)
+		return reflect.ValueOf(&v).Elem()
+	}
+}
+
+// WithFieldGen binds the generator g to the field at the field path path, such
+// as "User.Email". Instead of being decoded field by field, the value is
+// generated by g from a []byte in the layout. Any field can be bound,
+// including slices, maps and interfaces, which Fuzz cannot decode by itself.
+//
+// Generators cannot be inverted, so seeds cannot express generated values:
+// Add encodes a generated field as empty bytes, for which g generates what it
+// generates from an exhausted Source. Pass the same options to Add as to Fuzz.
+// Other ways of adding seeds do not know about generators, and Fuzz fails if
+// they were used.
+func WithFieldGen[V any](path string, g Gen[V]) Option {
+	return func(c *config) {
+		c.gens = append(c.gens, genBinding{t: reflect.TypeFor[V](), path: path, gen: bindGen(g)})
+	}
+}
+
+// WithGen binds the generator g to every field of type V, unless a generator
+// is bound to the field with WithFieldGen. See WithFieldGen.
+func WithGen[V any](g Gen[V]) Option {
+	return func(c *config) {
+		c.gens = append(c.gens, genBinding{t: reflect.TypeFor[V](), anyPath: true, gen: bindGen(g)})
+	}
+}
@@ -0,0 +1,244 @@
+package fuzzing
+
+import (
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGen_Combinators(t *testing.T) {
+	digit := IntRange(0, 9)
+	assert.Equal(t, 7, digit(NewSource([]byte{17})))
+	assert.Equal(t, 0, digit(NewSource(nil)))
+	assert.Equal(t, -5, IntRange(-5, 5)(NewSource(nil)))
+
+	assert.Equal(t, "x", Const("x")(NewSource([]byte{1, 2, 3})))
+	assert.Equal(t, "7", Map(digit, func(i int) string { return string(rune('0' + i)) })(NewSource([]byte{7})))
+
+	oneOf := OneOf(Const("a"), Const("b"), Const("c"))
+	assert.Equal(t, "c", oneOf(NewSource([]byte{2})))
+	assert.Equal(t, "a", oneOf(NewSource([]byte{3})))
+
+	weighted := Weighted(WeightedGen[string]{Weight: 1, Gen: Const("rare")}, WeightedGen[string]{Weight: 3, Gen: Const("common")})
+	assert.Equal(t, "rare", weighted(NewSource([]byte{0})))
+	assert.Equal(t, "common", weighted(NewSource([]byte{1})))
+	assert.Equal(t, "common", weighted(NewSource([]byte{3})))
+
+	slice := SliceOf(digit, 2)
+	assert.Equal(t, []int{3, 4}, slice(NewSource([]byte{1, 3, 1, 4, 1, 5})))
+	assert.Equal(t, []int{3}, slice(NewSource([]byte{1, 3, 0, 4})))
+	assert.Equal(t, []int{}, slice(NewSource(nil)))
+
+	m := MapOf(OneOf(Const("a"), Const("b")), digit, 3)
+	assert.Equal(t, map[string]int{"a": 2, "b": 3}, m(NewSource([]byte{1, 0, 1, 1, 1, 3, 1, 0, 2})))
+
+	ptrGen := Ptr(digit)
+	assert.Nil(t, ptrGen(NewSource([]byte{0, 5})))
+	assert.Equal(t, ptr(5), ptrGen(NewSource([]byte{1, 5})))
+
+	assert.Equal(t, tableIn{S: "foo", I: 1}, Any[tableIn]()(NewSource(EncodeBytes(tableIn{S: "foo", I: 1}))))
+}
+
+func TestGen_Filter(t *testing.T) {
+	even := Filter(IntRange(0, 9), func(i int) bool { return i%2 == 0 })
+	source := NewSource([]byte{1, 3, 4})
+	assert.Equal(t, 4, even(source))
+	assert.False(t, source.Rejected())
+
+	source = NewSource([]byte{1, 3, 5, 6})
+	even(source)
+	assert.True(t, source.Rejected())
+}
+
+func TestGen_Panics(t *testing.T) {
+	assert.Panics(t, func() { IntRange(1, 0) })
+	assert.Panics(t, func() { OneOf[int]() })
+	assert.Panics(t, func() { Weighted[int]() })
+	assert.Panics(t, func() { Weighted(WeightedGen[int]{Weight: 0, Gen: Const(1)}) })
+}
+
+type genUser struct {
+	Name  string
+	Email string
+	Tags  []string
+	Age   *int
+}
+
+var genEmail = Gen[string](func(s *Source) string {
+	return strings.Repeat("a", 1+s.IntN(3)) + "@example.com"
+})
+
+func TestLayoutOf_WithGen(t *testing.T) {
+	cfg := newConfig([]Option{WithFieldGen("Email", genEmail), WithGen(SliceOf(Const("tag"), 3))})
+	assert.Equal(t, []LayoutField{
+		{Path: "Name", Type: "string"},
+		{Path: "Email", Type: "[]uint8"},
+		{Path: "Tags", Type: "[]uint8"},
+		{Path: "Age", Type: "bool"},
+		{Path: "*Age", Type: "int"},
+	}, layoutOf(reflect.TypeFor[genUser](), cfg.gens).Fields)
+}
+
+func TestGenBindings_Check(t *testing.T) {
+	for name, opt := range map[string]Option{
+		"unknown path": WithFieldGen("Mail", genEmail),
+		"wrong type":   WithFieldGen("Age", Const(1)),
+		"unknown type": WithGen(Const(1.5)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, newConfig([]Option{opt}).gens.check(reflect.TypeFor[genUser]()))
+		})
+	}
+	cfg := newConfig([]Option{WithFieldGen("*Age", IntRange(0, 120)), WithFieldGen("Tags", SliceOf(genEmail, 2))})
+	assert.NoError(t, cfg.gens.check(reflect.TypeFor[genUser]()))
+}
+
+func TestFuzz_WithFieldGen(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	opts := []Option{
+		WithFieldGen("Email", Filter(genEmail, func(email string) bool { return email != "aaa@example.com" })),
+		WithFieldGen("Tags", SliceOf(Const("tag"), 3)),
+	}
+	mockF.EXPECT().Add("bob", []byte{}, []byte{}, false, 0)
+	Add(mockF, genUser{Name: "bob", Email: "ignored"}, opts...)
+
+	var target func(*testing.T, string, []byte, []byte, bool, int)
+	mockF.EXPECT().Fuzz(gomock.Any()).Do(func(fuzzTarget any) {
+		target = fuzzTarget.(func(*testing.T, string, []byte, []byte, bool, int))
+	})
+	var called []genUser
+	Fuzz(mockF, func(t *testing.T, user genUser) {
+		called = append(called, user)
+	}, opts...)
+
+	target(t, "bob", []byte{}, []byte{}, false, 0)
+	target(t, "alice", []byte{1}, []byte{1, 1}, true, 42)
+	skipped := false
+	t.Run("rejected", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		target(t, "eve", []byte{2, 2, 2}, []byte{}, false, 0)
+	})
+	assert.True(t, skipped)
+	assert.Equal(t, []genUser{
+		{Name: "bob", Email: "a@example.com", Tags: []string{}},
+		{Name: "alice", Email: "aa@example.com", Tags: []string{"tag", "tag"}, Age: ptr(42)},
+	}, called)
+}
+
+func TestFuzz_WithGen_SeedWithoutOptions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	mockF.EXPECT().Add("bob", "bob@example.com", false, 0)
+	AddNamed(mockF, "bob", genUser{Name: "bob", Email: "bob@example.com"})
+	mockF.EXPECT().Fatalf("fuzzing: %v", gomock.Any()).Do(func(format string, args ...any) {
+		assert.EqualError(t, args[0].(error), "seed#0 (bob) does not match the layout of the fuzzed type, add seeds with fuzzing.Add and the options passed to Fuzz")
+	})
+	Fuzz(mockF, func(t *testing.T, user genUser) {}, WithFieldGen("Email", genEmail))
+	assert.Nil(t, takeSeedNames(mockF).names)
+}
+
+func TestFuzz_WithGen_SeedHelpers(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	opts := []Option{WithFieldGen("Email", genEmail)}
+	gomock.InOrder(
+		mockF.EXPECT().Add("alice", []byte{}, false, 0),
+		mockF.EXPECT().Add("bob", []byte{}, false, 0),
+		mockF.EXPECT().Add("carol", []byte{}, true, 3),
+	)
+	AddAll(mockF, slices.Values([]genUser{{Name: "alice", Email: "alice@example.com"}}), opts...)
+	AddNamed(mockF, "bob", genUser{Name: "bob", Email: "bob@example.com"}, opts...)
+	AddTable(mockF, []genUser{{Name: "carol", Age: ptr(3)}}, func(u genUser) genUser { return u }, nil, opts...)
+	mutants := 0
+	mockF.EXPECT().Add(gomock.Any()).Do(func(args ...any) {
+		mutants++
+		assert.Equal(t, []byte{}, args[1])
+	}).AnyTimes()
+	AddMutated(mockF, genUser{Name: "dave"}, 10, nil, opts...)
+	assert.Greater(t, mutants, 0)
+
+	// The seeds match the layout, so Fuzz does not fail.
+	mockF.EXPECT().Fuzz(gomock.Any())
+	Fuzz(mockF, func(t *testing.T, user genUser) {}, opts...)
+
+	mockF.EXPECT().Fatalf("fuzzing: %v", gomock.Any()).Do(func(format string, args ...any) {
+		assert.EqualError(t, args[0].(error), `no field of type string at field path "Mail" in fuzzing.genUser to bind a generator to`)
+	})
+	AddNamed(mockF, "eve", genUser{}, WithFieldGen("Mail", genEmail))
+	takeSeedNames(mockF)
+}
+
+func TestFuzz_WithGen_Invalid(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	mockF.EXPECT().Fatalf("fuzzing: %v", gomock.Any())
+	Fuzz(mockF, func(t *testing.T, user genUser) {}, WithFieldGen("Mail", genEmail))
+}
+
+func TestByteTarget_WithGen(t *testing.T) {
+	var got genUser
+	target := ByteTarget(func(user genUser) int {
+		got = user
+		return 0
+	}, WithFieldGen("Email", genEmail))
+	assert.Equal(t, 0, target([]byte{3, 'b', 'o', 'b', 1, 2}))
+	assert.Equal(t, genUser{Name: "bob", Email: "aaa@example.com"}, got)
+}
+
+type genNilParent struct {
+	P *struct {
+		R io.Reader
+		N int
+	}
+}
+
+func TestBuildAnyTraverser_GenUnderNilPointer(t *testing.T) {
+	gens := newConfig([]Option{
+		WithGen(ReaderGen(nil)),
+		WithFieldGen("P.N", Filter(IntRange(1, 10), func(n int) bool { return false })),
+	}).gens
+	build := func(fields ...any) *buildAnyTraverser {
+		values := []reflect.Value{}
+		for _, field := range fields {
+			values = append(values, reflect.ValueOf(field))
+		}
+		builder := &buildAnyTraverser{fields: values, gens: gens}
+		assert.Equal(t, genNilParent{}, builder.traverseType(reflect.TypeFor[genNilParent]()).Interface())
+		return builder
+	}
+
+	// The canonical encoding of a nil P, as added by Add.
+	builder := build(false, []byte{}, []byte{})
+	assert.False(t, builder.nonCanonical)
+	// The generator would reject its input, but its value is thrown away.
+	assert.False(t, builder.rejected)
+
+	builder = build(false, []byte{1}, []byte{})
+	assert.True(t, builder.nonCanonical)
+	assert.False(t, builder.rejected)
+
+	builder = &buildAnyTraverser{fields: []reflect.Value{reflect.ValueOf(true), reflect.ValueOf([]byte{}), reflect.ValueOf([]byte{})}, gens: gens}
+	builder.traverseType(reflect.TypeFor[genNilParent]())
+	assert.True(t, builder.rejected)
+
+	target := ByteTarget(func(v genNilParent) int { return 0 }, WithGen(ReaderGen(nil)), WithFieldGen("P.N", IntRange(1, 10)))
+	assert.Equal(t, 0, target(nil))
+}
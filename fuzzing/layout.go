@@ -0,0 +1,225 @@
+package fuzzing
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Layout describes how a fuzzed type is flattened into the arguments of the
+// fuzz target, and so into the values of a `go test fuzz v1` corpus file.
+type Layout struct {
+	// Type is the fuzzed type.
+	Type string `json:"type"`
+	// Fields are the flattened fields, in the order they are passed to the
+	// fuzz target.
+	Fields []LayoutField `json:"fields"`
+}
+
+// LayoutField is a single flattened field of a Layout.
+//
+// The Path of a struct field is the field names joined by '.', like a Go
+// selector. A pointer is flattened into a bool, at the path of the pointer,
+// which is true if the pointer is set, followed by what it points at. As in
+// Go, pointers to structs are dereferenced implicitly, so the fields of
+// `N *Nested` are at `N.F`. Other pointers are dereferenced explicitly, so
+// the value of `S *string` is at `*S`. The path of a type that is not a
+// struct is empty.
+type LayoutField struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+// LayoutOf returns the layout of T.
+func LayoutOf[T any]() Layout {
+	return layoutOf(reflect.TypeFor[T](), nil)
+}
+
+// layoutOf returns the layout of tType, with a []byte for every field bound
+// to one of gens.
+func layoutOf(tType reflect.Type, gens genBindings) Layout {
+	fieldsTraverser := anyToFieldsTraverser{gens: gens}
+	fieldsTraverser.traverseType(tType)
+	layout := Layout{
+		Type:   tType.String(),
+		Fields: make([]LayoutField, 0, len(fieldsTraverser.fieldsTypes)),
+	}
+	for i, fieldType := range fieldsTraverser.fieldsTypes {
+		layout.Fields = append(layout.Fields, LayoutField{
+			Path: fieldsTraverser.fieldsPaths[i],
+			Type: fieldType.String(),
+		})
+	}
+	return layout
+}
+
+// Fingerprint returns a stable hash of the flattened fields of the layout. It
+// changes whenever a field is added, removed, reordered or changes type, but
+// not when the fuzzed type is renamed.
+func (l Layout) Fingerprint() string {
+	h := sha256.New()
+	for _, field := range l.Fields {
+		fmt.Fprintf(h, "%s %s\n", field.Path, field.Type)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:16]
+}
+
+// diffLayouts describes how the fields of layout to differ from those of
+// layout from, one change per line.
+func diffLayouts(from Layout, to Layout) []string {
+	fromTypes := map[string]string{}
+	fromIndexes := map[string]int{}
+	for i, field := range from.Fields {
+		fromTypes[field.Path] = field.Type
+		fromIndexes[field.Path] = i
+	}
+	toPaths := map[string]bool{}
+	added, changed, moved := []string{}, []string{}, []string{}
+	for i, field := range to.Fields {
+		toPaths[field.Path] = true
+		fromType, ok := fromTypes[field.Path]
+		switch {
+		case !ok:
+			added = append(added, displayFieldPath(field.Path))
+		case fromType != field.Type:
+			changed = append(changed, fmt.Sprintf("%s (%s -> %s)", displayFieldPath(field.Path), fromType, field.Type))
+		case fromIndexes[field.Path] != i:
+			moved = append(moved, fmt.Sprintf("%s (%d -> %d)", displayFieldPath(field.Path), fromIndexes[field.Path], i))
+		}
+	}
+	removed := []string{}
+	for _, field := range from.Fields {
+		if !toPaths[field.Path] {
+			removed = append(removed, displayFieldPath(field.Path))
+		}
+	}
+	diff := []string{}
+	for _, d := range []struct {
+		name  string
+		paths []string
+	}{
+		{"added", added},
+		{"removed", removed},
+		{"changed type", changed},
+		{"moved", moved},
+	} {
+		if len(d.paths) > 0 {
+			diff = append(diff, fmt.Sprintf("%s: %s", d.name, strings.Join(d.paths, ", ")))
+		}
+	}
+	return diff
+}
+
+func displayFieldPath(path string) string {
+	if path == "" {
+		return "<root>"
+	}
+	return path
+}
+
+func joinFieldPath(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// pointeeFieldPath returns the path of what a pointer of type t, at path
+// parent, points at.
+func pointeeFieldPath(parent string, t reflect.Type) string {
+	if t.Elem().Kind() == reflect.Struct {
+		return parent
+	}
+	return "*" + parent
+}
+
+// corpusZeroValues are the zero values of the types allowed in a corpus file.
+var corpusZeroValues = map[string]any{
+	"string":  "",
+	"[]uint8": []byte(nil),
+	"bool":    false,
+	"int":     0,
+	"int8":    int8(0),
+	"int16":   int16(0),
+	"int32":   int32(0),
+	"int64":   int64(0),
+	"uint":    uint(0),
+	"uint8":   uint8(0),
+	"uint16":  uint16(0),
+	"uint32":  uint32(0),
+	"uint64":  uint64(0),
+	"float32": float32(0),
+	"float64": float64(0),
+}
+
+// MigrateFields converts the flattened fields vals of layout from into the
+// flattened fields of layout to. Fields are matched by path. Fields that are
+// new, or whose type changed, are zero filled, and removed fields are dropped.
+func MigrateFields(from Layout, to Layout, vals []any) ([]any, error) {
+	if len(vals) != len(from.Fields) {
+		return nil, fmt.Errorf("%s has %d fields, got %d values", from.Type, len(from.Fields), len(vals))
+	}
+	byField := make(map[LayoutField]any, len(vals))
+	for i, field := range from.Fields {
+		if got := reflect.TypeOf(vals[i]).String(); got != field.Type {
+			return nil, fmt.Errorf("field %s of %s is %s, got %s", field.Path, from.Type, field.Type, got)
+		}
+		byField[field] = vals[i]
+	}
+	migrated := make([]any, 0, len(to.Fields))
+	for _, field := range to.Fields {
+		if val, ok := byField[field]; ok {
+			migrated = append(migrated, val)
+			continue
+		}
+		zero, ok := corpusZeroValues[field.Type]
+		if !ok {
+			return nil, fmt.Errorf("field %s of %s has unsupported type %s", field.Path, to.Type, field.Type)
+		}
+		migrated = append(migrated, zero)
+	}
+	return migrated, nil
+}
+
+// MigrateCorpus rewrites the `go test fuzz v1` corpus files at path, a file or
+// a directory such as testdata/fuzz/FuzzMyFunc, from layout from to layout to.
+// See MigrateFields. Files keep their names. Nothing is written unless every
+// file can be migrated. The paths of the migrated files are returned.
+func MigrateCorpus(path string, from Layout, to Layout) ([]string, error) {
+	paths, err := corpusFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	migrated := make([][]byte, 0, len(paths))
+	for _, p := range paths {
+		data, err := migrateCorpusFile(p, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		migrated = append(migrated, data)
+	}
+	for i, p := range paths {
+		if err := os.WriteFile(p, migrated[i], 0o644); err != nil {
+			return paths[:i], err
+		}
+	}
+	return paths, nil
+}
+
+func migrateCorpusFile(path string, from Layout, to Layout) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := unmarshalCorpusFile(data)
+	if err != nil {
+		return nil, err
+	}
+	migrated, err := MigrateFields(from, to, vals)
+	if err != nil {
+		return nil, err
+	}
+	return marshalCorpusFile(migrated)
+}
@@ -0,0 +1,176 @@
+package fuzzing
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLayoutOf(t *testing.T) {
+	type Nested struct {
+		F *float32
+		C int8
+	}
+	type Foo struct {
+		S  string
+		P  *int
+		i  int
+		N  *Nested
+		PP **bool
+	}
+	expected := Layout{
+		Type: "fuzzing.Foo",
+		Fields: []LayoutField{
+			{"S", "string"},
+			{"P", "bool"},
+			{"*P", "int"},
+			{"N", "bool"},
+			{"N.F", "bool"},
+			{"*N.F", "float32"},
+			{"N.C", "int8"},
+			{"PP", "bool"},
+			{"*PP", "bool"},
+			{"**PP", "bool"},
+		},
+	}
+	assert.Equal(t, expected, LayoutOf[Foo]())
+}
+
+func TestLayoutOf_Root(t *testing.T) {
+	assert.Equal(t, Layout{Type: "int", Fields: []LayoutField{{"", "int"}}}, LayoutOf[int]())
+	assert.Equal(t, Layout{Type: "*string", Fields: []LayoutField{{"", "bool"}, {"*", "string"}}}, LayoutOf[*string]())
+}
+
+func TestLayoutOf_MatchesAdd(t *testing.T) {
+	type Foo struct {
+		S string
+		P *int
+	}
+	fieldsTraverser := anyToFieldsTraverser{}
+	fieldsTraverser.traverseValue(reflect.ValueOf(Foo{P: ptr(1)}))
+	assert.Equal(t, []string{"S", "P", "*P"}, fieldsTraverser.fieldsPaths)
+}
+
+func TestMigrateFields(t *testing.T) {
+	type Old struct {
+		A string
+		B int
+		C *bool
+		D float64
+	}
+	type New struct {
+		C *bool
+		A string
+		E uint8
+		D int64
+	}
+	vals := []any{"foo", 42, true, true, 3.14}
+	migrated, err := MigrateFields(LayoutOf[Old](), LayoutOf[New](), vals)
+	require.NoError(t, err)
+	// C and A are moved, E is new and D changed type, so both are zero, B is dropped.
+	assert.Equal(t, []any{true, true, "foo", uint8(0), int64(0)}, migrated)
+}
+
+func TestMigrateFields_Mismatch(t *testing.T) {
+	type Old struct {
+		A string
+		B int
+	}
+	_, err := MigrateFields(LayoutOf[Old](), LayoutOf[Old](), []any{"foo"})
+	assert.ErrorContains(t, err, "has 2 fields, got 1 values")
+	_, err = MigrateFields(LayoutOf[Old](), LayoutOf[Old](), []any{"foo", "bar"})
+	assert.ErrorContains(t, err, "field B of fuzzing.Old is int, got string")
+}
+
+func TestMigrateCorpus(t *testing.T) {
+	type Old struct {
+		A string
+		B int
+	}
+	type New struct {
+		B int
+		C *string
+		A string
+	}
+	dir := t.TempDir()
+	path, err := WriteCorpusFile(dir, Old{A: "foo", B: 42})
+	require.NoError(t, err)
+
+	migrated, err := MigrateCorpus(dir, LayoutOf[Old](), LayoutOf[New]())
+	require.NoError(t, err)
+	assert.Equal(t, []string{path}, migrated)
+
+	decoded, err := DecodeCorpusFile[New](path)
+	require.NoError(t, err)
+	assert.Equal(t, New{A: "foo", B: 42}, decoded)
+}
+
+func TestMigrateCorpus_NothingWrittenOnError(t *testing.T) {
+	type Old struct {
+		A string
+	}
+	dir := t.TempDir()
+	path, err := WriteCorpusFile(dir, Old{A: "foo"})
+	require.NoError(t, err)
+	writeCorpusFile(t, dir, "bad", "go test fuzz v1\nint(1)\n")
+	before, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	_, err = MigrateCorpus(dir, LayoutOf[Old](), LayoutOf[int]())
+	assert.ErrorContains(t, err, filepath.Join(dir, "bad"))
+
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestLayout_Fingerprint(t *testing.T) {
+	type Foo struct {
+		S string
+		I int
+	}
+	type Bar struct {
+		S string
+		I int
+	}
+	type Swapped struct {
+		I int
+		S string
+	}
+	type Changed struct {
+		S string
+		I int64
+	}
+	fingerprint := LayoutOf[Foo]().Fingerprint()
+	assert.Len(t, fingerprint, 16)
+	assert.Equal(t, fingerprint, LayoutOf[Bar]().Fingerprint(), "renaming the type must not change the fingerprint")
+	assert.NotEqual(t, fingerprint, LayoutOf[Swapped]().Fingerprint())
+	assert.NotEqual(t, fingerprint, LayoutOf[Changed]().Fingerprint())
+}
+
+func TestDiffLayouts(t *testing.T) {
+	type Old struct {
+		A string
+		B int
+		C bool
+	}
+	type New struct {
+		C bool
+		A string
+		B int64
+		D *int
+	}
+	assert.Equal(t, []string{
+		"added: D, *D",
+		"removed: <root>",
+	}, diffLayouts(LayoutOf[int](), LayoutOf[struct{ D *int }]()))
+	assert.Equal(t, []string{
+		"added: D, *D",
+		"changed type: B (int -> int64)",
+		"moved: C (2 -> 0), A (0 -> 1)",
+	}, diffLayouts(LayoutOf[Old](), LayoutOf[New]()))
+	assert.Empty(t, diffLayouts(LayoutOf[Old](), LayoutOf[Old]()))
+}
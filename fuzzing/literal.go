@@ -0,0 +1,260 @@
+package fuzzing
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PtrHelper is the Go source of the helper used by GoLiteral to render
+// pointers to values that are not structs.
+const PtrHelper = "func ptr[T any](v T) *T { return &v }"
+
+// UsesPtrHelper reports whether the Go code src, such as a literal rendered
+// by GoLiteral, calls the ptr helper of PtrHelper. Unlike a search for
+// "ptr(", it ignores string contents and identifiers such as myptr or x.ptr.
+func UsesPtrHelper(src string) bool {
+	fset := token.NewFileSet()
+	s := scanner.Scanner{}
+	s.Init(fset.AddFile("", fset.Base(), len(src)), []byte(src), nil, 0)
+	var prev, prevPrev token.Token
+	var prevLit string
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return false
+		}
+		if tok == token.LPAREN && prev == token.IDENT && prevLit == "ptr" && prevPrev != token.PERIOD {
+			return true
+		}
+		prevPrev, prev, prevLit = prev, tok, lit
+	}
+}
+
+// GoLiteral renders v as Go source that evaluates to v, such as
+// `MyStruct{S: "\xff", B: true, Ptr: ptr(3)}`. The literal is meant to be
+// pasted into a regression test in the package that declares the type of v.
+//
+// Zero valued fields are omitted. Pointers to structs are rendered as
+// `&T{...}`, other pointers use the ptr helper (see PtrHelper). Floating point
+// values that are not constants, such as NaN, use the math package.
+func GoLiteral(v any) string {
+	value := reflect.ValueOf(v)
+	if !value.IsValid() {
+		return "nil"
+	}
+	r := literalRenderer{pkgPath: literalPkgPath(value.Type())}
+	return r.render(value, true)
+}
+
+type literalRenderer struct {
+	// pkgPath is the package the literal is written for. Types from other
+	// packages are qualified with their package name.
+	pkgPath string
+}
+
+// render returns the literal for value. If typed is false, the literal is
+// assigned to a location of value's type, so untyped constants are enough.
+func (r *literalRenderer) render(value reflect.Value, typed bool) string {
+	t := value.Type()
+	switch t.Kind() {
+	case reflect.Bool:
+		return r.convert(t, strconv.FormatBool(value.Bool()), typed, reflect.Bool)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return r.convert(t, strconv.FormatInt(value.Int(), 10), typed, reflect.Int)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return r.convert(t, strconv.FormatUint(value.Uint(), 10), typed, reflect.Int)
+	case reflect.Float32, reflect.Float64:
+		f := value.Float()
+		if isConstantFloat(f) {
+			return r.convert(t, formatFloat(f, t.Bits()), typed, reflect.Float64)
+		}
+		// Not a constant, so the conversion is needed even when untyped.
+		return r.convert(t, formatFloat(f, t.Bits()), true, reflect.Float64)
+	case reflect.Complex64, reflect.Complex128:
+		c := value.Complex()
+		partBits := t.Bits() / 2
+		if isConstantFloat(real(c)) && isConstantFloat(imag(c)) {
+			lit := fmt.Sprintf("complex(%s, %s)", formatFloat(real(c), partBits), formatFloat(imag(c), partBits))
+			return r.convert(t, lit, typed, reflect.Complex128)
+		}
+		lit := fmt.Sprintf("complex(%s, %s)", r.floatPart(real(c), partBits), r.floatPart(imag(c), partBits))
+		return r.convert(t, lit, true, reflect.Complex128)
+	case reflect.String:
+		return r.convert(t, strconv.Quote(value.String()), typed, reflect.String)
+	case reflect.Pointer:
+		if value.IsNil() {
+			return r.nilLiteral(t, typed)
+		}
+		if t.Elem().Kind() == reflect.Struct {
+			return "&" + r.render(value.Elem(), true)
+		}
+		return fmt.Sprintf("ptr(%s)", r.render(value.Elem(), true))
+	case reflect.Struct:
+		fields := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			structField := t.Field(i)
+			fieldValue := value.Field(i)
+			if !structField.IsExported() || isZeroValue(fieldValue) {
+				continue
+			}
+			fields = append(fields, fmt.Sprintf("%s: %s", structField.Name, r.render(fieldValue, false)))
+		}
+		return fmt.Sprintf("%s{%s}", r.typeName(t), strings.Join(fields, ", "))
+	case reflect.Array:
+		elems := []string{}
+		for i := 0; i < value.Len(); i++ {
+			elems = append(elems, r.render(value.Index(i), false))
+		}
+		return fmt.Sprintf("%s{%s}", r.typeName(t), strings.Join(elems, ", "))
+	case reflect.Slice:
+		if value.IsNil() {
+			return r.nilLiteral(t, typed)
+		}
+		elems := []string{}
+		for i := 0; i < value.Len(); i++ {
+			elems = append(elems, r.render(value.Index(i), false))
+		}
+		return fmt.Sprintf("%s{%s}", r.typeName(t), strings.Join(elems, ", "))
+	case reflect.Map:
+		if value.IsNil() {
+			return r.nilLiteral(t, typed)
+		}
+		entries := []string{}
+		iter := value.MapRange()
+		for iter.Next() {
+			entries = append(entries, fmt.Sprintf("%s: %s", r.render(iter.Key(), false), r.render(iter.Value(), false)))
+		}
+		// Map iteration order is random, sort to get a stable literal.
+		sort.Strings(entries)
+		return fmt.Sprintf("%s{%s}", r.typeName(t), strings.Join(entries, ", "))
+	case reflect.Interface:
+		if value.IsNil() {
+			return r.nilLiteral(t, typed)
+		}
+		return r.render(value.Elem(), true)
+	default:
+		// Channels, functions and unsafe pointers cannot be written as literals.
+		if value.IsNil() {
+			return r.nilLiteral(t, typed)
+		}
+		return fmt.Sprintf("nil /* %v cannot be rendered */", t)
+	}
+}
+
+// convert wraps lit in a conversion to t when the literal must be typed and
+// the default type of the constant is not t.
+func (r *literalRenderer) convert(t reflect.Type, lit string, typed bool, defaultKind reflect.Kind) string {
+	if !typed {
+		return lit
+	}
+	if t.PkgPath() == "" && t.Kind() == defaultKind {
+		return lit
+	}
+	return fmt.Sprintf("%s(%s)", r.typeName(t), lit)
+}
+
+func (r *literalRenderer) nilLiteral(t reflect.Type, typed bool) string {
+	if !typed {
+		return "nil"
+	}
+	return fmt.Sprintf("(%s)(nil)", r.typeName(t))
+}
+
+func (r *literalRenderer) floatPart(f float64, bits int) string {
+	if bits == 32 {
+		return fmt.Sprintf("float32(%s)", formatFloat(f, bits))
+	}
+	return formatFloat(f, bits)
+}
+
+func (r *literalRenderer) typeName(t reflect.Type) string {
+	if t.Name() != "" {
+		if t.PkgPath() == "" || t.PkgPath() == r.pkgPath {
+			return t.Name()
+		}
+		return t.String()
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return "*" + r.typeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + r.typeName(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), r.typeName(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", r.typeName(t.Key()), r.typeName(t.Elem()))
+	default:
+		return t.String()
+	}
+}
+
+// literalPkgPath returns the package of the named type that t is built from.
+func literalPkgPath(t reflect.Type) string {
+	for t.Name() == "" {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return ""
+		}
+	}
+	return t.PkgPath()
+}
+
+// isZeroValue is like reflect.Value.IsZero, except that negative zero is not
+// considered to be zero.
+func isZeroValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return math.Float64bits(value.Float()) == 0
+	case reflect.Complex64, reflect.Complex128:
+		c := value.Complex()
+		return math.Float64bits(real(c)) == 0 && math.Float64bits(imag(c)) == 0
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if !isZeroValue(value.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if !isZeroValue(value.Index(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return value.IsZero()
+	}
+}
+
+func isConstantFloat(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0) && !(f == 0 && math.Signbit(f))
+}
+
+// formatFloat returns a floating point literal. Constants always contain a
+// '.' or an exponent so that their default type is float64.
+func formatFloat(f float64, bits int) string {
+	switch {
+	case math.IsNaN(f):
+		return "math.NaN()"
+	case math.IsInf(f, 1):
+		return "math.Inf(1)"
+	case math.IsInf(f, -1):
+		return "math.Inf(-1)"
+	case f == 0 && math.Signbit(f):
+		return "math.Copysign(0, -1)"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
@@ -0,0 +1,104 @@
+package fuzzing
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+type literalInt int
+
+type literalNested struct {
+	F *float32
+	C complex64
+}
+
+type literalFoo struct {
+	S  string
+	B  bool
+	I  int
+	I8 int8
+	U  uint16
+	F  float64
+	P  *int
+	P8 *int8
+	PF *float64
+	N  *literalNested
+	L  literalInt
+	PL *literalInt
+	i  int
+}
+
+func TestGoLiteral_Struct(t *testing.T) {
+	foo := literalFoo{
+		S:  "\xff",
+		B:  true,
+		I:  -3,
+		I8: 8,
+		U:  16,
+		F:  42,
+		P:  ptr(3),
+		P8: ptr(int8(4)),
+		PF: ptr(2.0),
+		N:  &literalNested{F: ptr(float32(3.14)), C: complex64(complex(1, 2))},
+		L:  7,
+		PL: ptr(literalInt(7)),
+		i:  1,
+	}
+	expected := `literalFoo{S: "\xff", B: true, I: -3, I8: 8, U: 16, F: 42.0, P: ptr(3), P8: ptr(int8(4)), ` +
+		`PF: ptr(2.0), N: &literalNested{F: ptr(float32(3.14)), C: complex(1.0, 2.0)}, L: 7, PL: ptr(literalInt(7))}`
+	assert.Equal(t, expected, GoLiteral(foo))
+}
+
+func TestGoLiteral_ZeroFieldsOmitted(t *testing.T) {
+	assert.Equal(t, "literalFoo{}", GoLiteral(literalFoo{}))
+	assert.Equal(t, "&literalFoo{}", GoLiteral(&literalFoo{}))
+}
+
+func TestGoLiteral_Root(t *testing.T) {
+	assert.Equal(t, "42", GoLiteral(42))
+	assert.Equal(t, "int8(42)", GoLiteral(int8(42)))
+	assert.Equal(t, "literalInt(42)", GoLiteral(literalInt(42)))
+	assert.Equal(t, `"foo"`, GoLiteral("foo"))
+	assert.Equal(t, "nil", GoLiteral(nil))
+	assert.Equal(t, "(*int)(nil)", GoLiteral((*int)(nil)))
+	assert.Equal(t, "[]int{1, 2}", GoLiteral([]int{1, 2}))
+	assert.Equal(t, `map[string]int{"a": 1, "b": 2}`, GoLiteral(map[string]int{"b": 2, "a": 1}))
+}
+
+func TestGoLiteral_NonConstantFloats(t *testing.T) {
+	type Floats struct {
+		A float64
+		B float32
+		C float64
+		D complex64
+	}
+	floats := Floats{
+		A: math.NaN(),
+		B: float32(math.Inf(1)),
+		C: math.Copysign(0, -1),
+		D: complex(float32(math.Inf(-1)), 1),
+	}
+	expected := "Floats{A: math.NaN(), B: float32(math.Inf(1)), C: math.Copysign(0, -1), " +
+		"D: complex64(complex(float32(math.Inf(-1)), float32(1.0)))}"
+	assert.Equal(t, expected, GoLiteral(floats))
+}
+
+func TestDescribeFailingInput_PtrHelper(t *testing.T) {
+	description := describeFailingInput("", literalFoo{P: ptr(3)})
+	assert.Contains(t, description, "as Go literal:\n\tliteralFoo{P: ptr(3)}")
+	assert.Contains(t, description, PtrHelper)
+
+	description = describeFailingInput("", literalFoo{I: 3})
+	assert.Contains(t, description, "as Go literal:\n\tliteralFoo{I: 3}")
+	assert.NotContains(t, description, PtrHelper)
+}
+
+func TestUsesPtrHelper(t *testing.T) {
+	assert.True(t, UsesPtrHelper(GoLiteral(literalFoo{P: ptr(1)})))
+	assert.True(t, UsesPtrHelper(`machine.Run(t, Put{Val: ptr(1)})`))
+	assert.False(t, UsesPtrHelper(GoLiteral(literalFoo{S: "ptr(1)"})))
+	assert.False(t, UsesPtrHelper(`myptr(1)`))
+	assert.False(t, UsesPtrHelper(`x.ptr(1)`))
+	assert.False(t, UsesPtrHelper(GoLiteral(literalFoo{N: &literalNested{}})))
+}
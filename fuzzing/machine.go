@@ -0,0 +1,255 @@
+package fuzzing
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+// Op is an operation of a stateful test against the system under test S, such
+// as a Put or a Delete. The exported fields of the type implementing Op are
+// its arguments, and are decoded like the fields of the type passed to Fuzz.
+// Apply returns an error if the system under test misbehaved.
+type Op[S any] interface {
+	Apply(s S) error
+}
+
+// Machine fuzzes sequences of operations against a system under test, for
+// bugs that only show under specific call orders. A failing sequence is
+// shrunk, by removing steps for as long as it keeps failing, before it is
+// reported.
+//
+//	fuzzing.Machine[*Cache]{
+//		New:       func() *Cache { return NewCache(8) },
+//		Ops:       []fuzzing.Op[*Cache]{Put{}, Get{}, Delete{}},
+//		Invariant: (*Cache).Check,
+//	}.Fuzz(f)
+type Machine[S any] struct {
+	// New returns a new system under test, for every sequence.
+	New func() S
+	// Ops are a value of every type of operation. Only the types matter, the
+	// values of their fields are ignored.
+	Ops []Op[S]
+	// Invariant, if set, is checked after every step.
+	Invariant func(s S) error
+	// MaxSteps is the maximum number of steps in a sequence, 32 if zero.
+	MaxSteps int
+}
+
+// codec returns the codec of the sequences of m.
+func (m Machine[S]) codec(gens genBindings) (*sequenceCodec, error) {
+	if m.New == nil {
+		return nil, fmt.Errorf("Machine.New is nil")
+	}
+	return newOpsCodec(m.Ops, m.MaxSteps, gens)
+}
+
+// Fuzz fuzzes sequences of the operations of m. The fuzz target takes a single
+// []byte that encodes the sequence. Generators bound with WithGen and
+// WithFieldGen apply to the fields of the operations, with field paths
+// relative to the operation, and AllowNonCanonicalInputs is honored. Other
+// options make Fuzz fail, as they do not apply to sequences.
+func (m Machine[S]) Fuzz(f TestingF, opts ...Option) {
+	cfg := newConfig(opts)
+	codec, err := m.codec(cfg.gens)
+	if err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	fuzzSequence(f, cfg, codec, stepAs[Op[S]], m.check)
+}
+
+// Add adds the sequence ops to the corpus of f. Pass the options passed to
+// Fuzz.
+func (m Machine[S]) Add(f TestingF, ops []Op[S], opts ...Option) {
+	codec, err := m.codec(newConfig(opts).gens)
+	if err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	addSequence(f, codec, stepValues(ops))
+}
+
+// Run runs the sequence ops, and reports a failure to t like Fuzz does. Use it
+// to turn a reported sequence into a regression test.
+func (m Machine[S]) Run(t TestingT, ops ...Op[S]) {
+	t.Helper()
+	m.check(t, "", ops)
+}
+
+func (m Machine[S]) check(t failureReporter, seedName string, ops []Op[S]) {
+	t.Helper()
+	checkSequence(t, seedName, ops, m.run)
+}
+
+// run applies ops to a new system under test.
+func (m Machine[S]) run(ops []Op[S]) sequenceRun {
+	s := m.New()
+	for i, op := range ops {
+		if failure := m.runStep(s, op); failure != "" {
+			return sequenceRun{failed: i, failure: failure}
+		}
+	}
+	return sequenceRun{failed: -1}
+}
+
+func (m Machine[S]) runStep(s S, op Op[S]) (failure string) {
+	defer recoverStep(&failure)
+	if err := op.Apply(s); err != nil {
+		return err.Error()
+	}
+	return checkInvariant(m.Invariant, s)
+}
+
+// newOpsCodec returns the codec of sequences of ops, which are a value of
+// every type of operation.
+func newOpsCodec[O any](ops []O, maxSteps int, gens genBindings) (*sequenceCodec, error) {
+	types := make([]reflect.Type, 0, len(ops))
+	for _, op := range ops {
+		if any(op) == nil {
+			return nil, fmt.Errorf("Ops holds a nil operation")
+		}
+		types = append(types, reflect.TypeOf(op))
+	}
+	return newSequenceCodec(types, maxSteps, gens)
+}
+
+// fuzzSequence fuzzes sequences of operations of type O decoded by codec, and
+// checks them with check. toOp converts a decoded step to an operation.
+func fuzzSequence[O any](f TestingF, cfg *config, codec *sequenceCodec, toOp func(step reflect.Value) O, check func(t failureReporter, seedName string, ops []O)) {
+	if err := cfg.checkSequenceOptions(); err != nil {
+		takeSeedNames(f)
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	if err := checkLayoutSidecar(CorpusDir(f.Name()), codec.layout(), isFuzzing(), sequenceLayout); err != nil {
+		takeSeedNames(f)
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	if err := checkSeedSlots(f, []reflect.Type{reflect.TypeFor[[]byte]()}); err != nil {
+		takeSeedNames(f)
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	seedNames := takeSeedNames(f)
+	skipNonCanonical := isFuzzing() && !cfg.allowNonCanonical
+	stats := &rejectionStats{}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		steps, builders := codec.decode(data)
+		ops := make([]O, 0, len(steps))
+		for i, step := range steps {
+			if skipNonCanonical && builders[i].nonCanonical {
+				t.Skip("non-canonical input")
+			}
+			if builders[i].rejected {
+				stats.record(true)
+				t.Skip("input rejected by generator")
+			}
+			ops = append(ops, toOp(step))
+		}
+		check(t, seedName(seedNames, t.Name(), []reflect.Value{reflect.ValueOf(data)}), ops)
+		stats.record(false)
+	})
+}
+
+// stepAs converts a decoded step to the operation it is.
+func stepAs[O any](step reflect.Value) O {
+	return step.Interface().(O)
+}
+
+// stepValues converts operations to the steps that encode them.
+func stepValues[O any](ops []O) []reflect.Value {
+	steps := make([]reflect.Value, 0, len(ops))
+	for _, op := range ops {
+		steps = append(steps, reflect.ValueOf(op))
+	}
+	return steps
+}
+
+// addSequence adds the sequence steps, encoded by codec, to the corpus of f.
+func addSequence(f TestingF, codec *sequenceCodec, steps []reflect.Value) {
+	data, err := codec.encode(steps)
+	if err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	addFields(f, "", []any{data})
+}
+
+// sequenceRun is the outcome of running a sequence of operations.
+type sequenceRun struct {
+	// failed is the index of the first failing step, or -1.
+	failed  int
+	failure string
+	// results are what the steps that ran returned, rendered as Go code, for
+	// operations that return something.
+	results []string
+}
+
+// checkSequence runs ops with run, and reports the shortest failing
+// subsequence if they fail.
+func checkSequence[O any](t failureReporter, seedName string, ops []O, run func([]O) sequenceRun) {
+	t.Helper()
+	outcome := run(ops)
+	if outcome.failed < 0 {
+		return
+	}
+	shortest := shrinkSequence(ops[:outcome.failed+1], func(candidate []O) bool {
+		return run(candidate).failed >= 0
+	})
+	t.Errorf("%s", describeFailingSequence(seedName, len(ops), shortest, run(shortest)))
+}
+
+// recoverStep turns a panic in a step into its failure.
+func recoverStep(failure *string) {
+	if recovered := recover(); recovered != nil {
+		*failure = fmt.Sprintf("panic: %v\n%s", recovered, debug.Stack())
+	}
+}
+
+func checkInvariant[S any](invariant func(s S) error, s S) string {
+	if invariant == nil {
+		return ""
+	}
+	if err := invariant(s); err != nil {
+		return fmt.Sprintf("invariant: %v", err)
+	}
+	return ""
+}
+
+// describeFailingSequence describes the shortest failing sequence found, one
+// step per line, and as Go code that runs it.
+func describeFailingSequence[O any](seedName string, decodedSteps int, ops []O, outcome sequenceRun) string {
+	sb := &strings.Builder{}
+	if seedName != "" {
+		fmt.Fprintf(sb, "seed %q\n", seedName)
+	}
+	fmt.Fprintf(sb, "step %d failed: %s\n", outcome.failed, outcome.failure)
+	fmt.Fprintf(sb, "shortest failing sequence, %d of %d decoded steps:", len(ops), decodedSteps)
+	literals := make([]string, 0, len(ops))
+	for i, op := range ops {
+		literal := renderStep(op)
+		literals = append(literals, literal)
+		fmt.Fprintf(sb, "\n\t%d: %s", i, literal)
+		if i < len(outcome.results) && outcome.results[i] != "" {
+			fmt.Fprintf(sb, " = %s", outcome.results[i])
+		}
+	}
+	code := fmt.Sprintf("machine.Run(t, %s)", strings.Join(literals, ", "))
+	fmt.Fprintf(sb, "\nas Go code:\n\t%s", code)
+	if UsesPtrHelper(code) {
+		fmt.Fprintf(sb, "\n\t// %s", PtrHelper)
+	}
+	return sb.String()
+}
+
+// renderStep renders an operation as Go code.
+func renderStep(op any) string {
+	if call, ok := op.(MethodCall); ok {
+		return call.goCode()
+	}
+	return GoLiteral(op)
+}
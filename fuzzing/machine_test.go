@@ -0,0 +1,165 @@
+package fuzzing
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"strings"
+	"testing"
+)
+
+// machineStore counts its keys, and miscounts deleted keys that are missing.
+type machineStore struct {
+	m map[string]int
+	n int
+}
+
+func (s *machineStore) check() error {
+	if s.n != len(s.m) {
+		return fmt.Errorf("n is %d, but there are %d keys", s.n, len(s.m))
+	}
+	return nil
+}
+
+type machinePut struct {
+	Key string
+	Val int
+}
+
+func (op machinePut) Apply(s *machineStore) error {
+	if _, ok := s.m[op.Key]; !ok {
+		s.n++
+	}
+	s.m[op.Key] = op.Val
+	return nil
+}
+
+type machineDelete struct {
+	Key string
+}
+
+func (op *machineDelete) Apply(s *machineStore) error {
+	delete(s.m, op.Key)
+	s.n--
+	return nil
+}
+
+type machineGet struct {
+	Key string
+}
+
+func (op machineGet) Apply(s *machineStore) error {
+	if op.Key == "panic" {
+		panic("uh oh")
+	}
+	if op.Key == "error" {
+		return errors.New("get failed")
+	}
+	return nil
+}
+
+func newMachineStore() *machineStore {
+	return &machineStore{m: map[string]int{}}
+}
+
+var storeMachine = Machine[*machineStore]{
+	New:       newMachineStore,
+	Ops:       []Op[*machineStore]{machinePut{}, &machineDelete{}, machineGet{}},
+	Invariant: (*machineStore).check,
+}
+
+func TestMachine_Run(t *testing.T) {
+	r := &fakeReporter{}
+	storeMachine.check(r, "", []Op[*machineStore]{machinePut{Key: "a", Val: 1}, &machineDelete{Key: "a"}})
+	assert.Empty(t, r.errors)
+
+	storeMachine.check(r, "", []Op[*machineStore]{
+		machinePut{Key: "a", Val: 1},
+		machineGet{Key: "a"},
+		&machineDelete{Key: "b"},
+		machinePut{Key: "c"},
+	})
+	require.Len(t, r.errors, 1)
+	expected := "step 0 failed: invariant: n is -1, but there are 0 keys" +
+		"\nshortest failing sequence, 1 of 4 decoded steps:" +
+		"\n\t0: &machineDelete{Key: \"b\"}" +
+		"\nas Go code:" +
+		"\n\tmachine.Run(t, &machineDelete{Key: \"b\"})"
+	assert.Equal(t, expected, r.errors[0])
+}
+
+func TestMachine_RunFailures(t *testing.T) {
+	r := &fakeReporter{}
+	storeMachine.check(r, "seed", []Op[*machineStore]{machinePut{Key: "a"}, machineGet{Key: "error"}})
+	require.Len(t, r.errors, 1)
+	assert.True(t, strings.HasPrefix(r.errors[0], "seed \"seed\"\nstep 0 failed: get failed\n"), r.errors[0])
+
+	storeMachine.check(r, "", []Op[*machineStore]{machineGet{Key: "panic"}})
+	require.Len(t, r.errors, 2)
+	assert.True(t, strings.HasPrefix(r.errors[1], "step 0 failed: panic: uh oh\n"), r.errors[1])
+}
+
+func TestMachine_Fuzz(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	var seed []byte
+	mockF.EXPECT().Add(gomock.Any()).Do(func(args ...any) {
+		seed = args[0].([]byte)
+	})
+	storeMachine.Add(mockF, []Op[*machineStore]{machinePut{Key: "a", Val: 1}, &machineDelete{Key: "a"}, machinePut{Key: "b", Val: 2}})
+
+	var target func(*testing.T, []byte)
+	mockF.EXPECT().Fuzz(gomock.Any()).Do(func(fuzzTarget any) {
+		target = fuzzTarget.(func(*testing.T, []byte))
+	})
+	var last *machineStore
+	machine := storeMachine
+	machine.New = func() *machineStore {
+		last = newMachineStore()
+		return last
+	}
+	machine.Fuzz(mockF)
+
+	target(t, seed)
+	assert.Equal(t, &machineStore{m: map[string]int{"b": 2}, n: 1}, last)
+	target(t, nil)
+	assert.Equal(t, newMachineStore(), last)
+}
+
+func TestMachine_Invalid(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	gomock.InOrder(
+		mockF.EXPECT().Fatalf("fuzzing: %v", gomock.Any()).Do(func(format string, args ...any) {
+			assert.EqualError(t, args[0].(error), "Machine.New is nil")
+		}),
+		mockF.EXPECT().Fatalf("fuzzing: %v", gomock.Any()).Do(func(format string, args ...any) {
+			assert.EqualError(t, args[0].(error), "Ops holds a nil operation")
+		}),
+		mockF.EXPECT().Fatalf("fuzzing: %v", gomock.Any()).Do(func(format string, args ...any) {
+			assert.EqualError(t, args[0].(error), "*fuzzing.machineDelete is not one of the step types")
+		}),
+	)
+	Machine[*machineStore]{Ops: storeMachine.Ops}.Fuzz(mockF)
+	Machine[*machineStore]{New: newMachineStore, Ops: []Op[*machineStore]{nil}}.Fuzz(mockF)
+	Machine[*machineStore]{New: newMachineStore, Ops: []Op[*machineStore]{machinePut{}}}.Add(mockF, []Op[*machineStore]{&machineDelete{}})
+}
+
+func TestMachine_UnsupportedOptions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	mockF.EXPECT().Fatalf("fuzzing: %v", gomock.Any()).Do(func(format string, args ...any) {
+		assert.EqualError(t, args[0].(error), "stateful tests do not support WithPrecondition, WithBoundarySeeds, only generators and AllowNonCanonicalInputs")
+	})
+	storeMachine.Fuzz(mockF, WithPrecondition(func(op machinePut) bool { return true }), WithBoundarySeeds(), AllowNonCanonicalInputs())
+}
@@ -0,0 +1,333 @@
+package fuzzing
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Methods fuzzes sequences of calls to the methods of the interface I,
+// discovered by reflection, against the implementation returned by New. The
+// arguments of every call are decoded like the fields of the type passed to
+// Fuzz. Arguments of kinds that are never decoded, like interfaces, slices and
+// maps, must be bound to a generator, at field path Arg0 for the first
+// argument, Arg1 for the second, and so on. A call fails if it panics, or if
+// it violates the postcondition of its method. Errors returned by methods are
+// results like any other, so check them in postconditions if they matter.
+//
+//	fuzzing.Methods[Repository]{
+//		New: func() Repository { return NewMemoryRepository() },
+//		Postconditions: map[string]any{
+//			"Get": func(id string, user User, err error) error { ... },
+//		},
+//	}.Fuzz(f)
+type Methods[I any] struct {
+	// New returns a new implementation of I, for every sequence.
+	New func() I
+	// Postconditions are keyed by method name. A postcondition is a function
+	// that takes the arguments of a call of the method, followed by its
+	// results, and returns an error if they violate the contract of the method.
+	Postconditions map[string]any
+	// Invariant, if set, is checked after every call.
+	Invariant func(impl I) error
+	// MaxSteps is the maximum number of calls in a sequence, 32 if zero.
+	MaxSteps int
+}
+
+// MethodCall is a call of a method of the interface fuzzed by Methods, or of
+// a Fake.
+type MethodCall struct {
+	Method string
+	Args   []any
+	// Results are the results returned by a Fake. They are not set for calls
+	// fuzzed by Methods.
+	Results []any
+}
+
+// Call returns a call of the method named method with args, for Methods.Add
+// and Methods.Run. A nil argument is the zero value of its parameter.
+func Call(method string, args ...any) MethodCall {
+	return MethodCall{Method: method, Args: args}
+}
+
+// goCode renders the call as the call of Call that returns it.
+func (c MethodCall) goCode() string {
+	literals := []string{fmt.Sprintf("%q", c.Method)}
+	for _, arg := range c.Args {
+		literals = append(literals, GoLiteral(arg))
+	}
+	return fmt.Sprintf("fuzzing.Call(%s)", strings.Join(literals, ", "))
+}
+
+// interfaceMethod is an exported method of an interface fuzzed by Methods.
+type interfaceMethod struct {
+	name string
+	// index is the index of the method in the interface.
+	index int
+	t     reflect.Type
+	// args is a struct with a field Arg0, Arg1 and so on for every parameter
+	// of the method, and a marker field named after the method, which keeps
+	// the args of methods with the same parameters apart.
+	args          reflect.Type
+	postcondition reflect.Value
+}
+
+// methodSet are the methods of an interface fuzzed by Methods.
+type methodSet struct {
+	methods []interfaceMethod
+	byName  map[string]int
+	byArgs  map[reflect.Type]int
+	codec   *sequenceCodec
+}
+
+// methodSet discovers the methods of I, and checks the postconditions.
+func (m Methods[I]) methodSet(gens genBindings) (*methodSet, error) {
+	iType := reflect.TypeFor[I]()
+	if iType.Kind() != reflect.Interface {
+		return nil, fmt.Errorf("Methods needs an interface type, got %v", iType)
+	}
+	if m.New == nil {
+		return nil, fmt.Errorf("Methods.New is nil")
+	}
+	set := &methodSet{byName: map[string]int{}, byArgs: map[reflect.Type]int{}}
+	types := []reflect.Type{}
+	for i := 0; i < iType.NumMethod(); i++ {
+		method := iType.Method(i)
+		if !method.IsExported() {
+			continue
+		}
+		fields := []reflect.StructField{}
+		for j := 0; j < method.Type.NumIn(); j++ {
+			fields = append(fields, reflect.StructField{
+				Name: fmt.Sprintf("Arg%d", j),
+				Type: method.Type.In(j),
+			})
+		}
+		fields = append(fields, reflect.StructField{
+			Name: "Method" + method.Name,
+			Type: reflect.TypeFor[struct{}](),
+		})
+		args := reflect.StructOf(fields)
+		set.byName[method.Name] = len(set.methods)
+		set.byArgs[args] = len(set.methods)
+		set.methods = append(set.methods, interfaceMethod{name: method.Name, index: i, t: method.Type, args: args})
+		types = append(types, args)
+	}
+	if len(set.methods) == 0 {
+		return nil, fmt.Errorf("%v has no exported methods", iType)
+	}
+	for name, postcondition := range m.Postconditions {
+		i, ok := set.byName[name]
+		if !ok {
+			return nil, fmt.Errorf("postcondition of %s, which is not a method of %v", name, iType)
+		}
+		if err := checkPostcondition(set.methods[i].t, postcondition); err != nil {
+			return nil, fmt.Errorf("postcondition of %s: %w", name, err)
+		}
+		set.methods[i].postcondition = reflect.ValueOf(postcondition)
+	}
+	codec, err := newSequenceCodec(types, m.MaxSteps, gens)
+	if err != nil {
+		return nil, err
+	}
+	set.codec = codec
+	return set, nil
+}
+
+// decodingSet returns the methodSet of I, for decoding and encoding calls,
+// which needs a generator for every argument that is never decoded. Run only
+// calls methods with the arguments it is given, so it does not.
+func (m Methods[I]) decodingSet(gens genBindings) (*methodSet, error) {
+	set, err := m.methodSet(gens)
+	if err != nil {
+		return nil, err
+	}
+	for _, method := range set.methods {
+		for j := 0; j < method.t.NumIn(); j++ {
+			name := fmt.Sprintf("Arg%d", j)
+			argType := method.t.In(j)
+			if !isDecodedKind(argType.Kind()) && gens.lookup(name, argType) == nil {
+				return nil, fmt.Errorf("argument %d of %s is %v, which is never decoded, bind a generator to it with WithFieldGen(%q, ...)",
+					j, method.name, argType, name)
+			}
+		}
+	}
+	return set, nil
+}
+
+// isDecodedKind reports whether values of kind k are decoded from the input.
+// Values of other kinds are always zero, unless bound to a generator.
+func isDecodedKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Array, reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Slice, reflect.UnsafePointer:
+		return false
+	}
+	return true
+}
+
+// checkPostcondition returns an error if postcondition is not a function that
+// takes the parameters of methodType, followed by its results, and returns an
+// error.
+func checkPostcondition(methodType reflect.Type, postcondition any) error {
+	in := []reflect.Type{}
+	for i := 0; i < methodType.NumIn(); i++ {
+		in = append(in, methodType.In(i))
+	}
+	for i := 0; i < methodType.NumOut(); i++ {
+		in = append(in, methodType.Out(i))
+	}
+	want := reflect.FuncOf(in, []reflect.Type{errorType}, false)
+	if reflect.TypeOf(postcondition) != want {
+		return fmt.Errorf("got %T, want %v", postcondition, want)
+	}
+	return nil
+}
+
+// Fuzz fuzzes sequences of calls of the methods of I, like Machine.Fuzz.
+// Generators bound with WithFieldGen apply to the field paths Arg0, Arg1 and
+// so on of the arguments of every method.
+func (m Methods[I]) Fuzz(f TestingF, opts ...Option) {
+	cfg := newConfig(opts)
+	set, err := m.decodingSet(cfg.gens)
+	if err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	fuzzSequence(f, cfg, set.codec, set.call, func(t failureReporter, seedName string, calls []MethodCall) {
+		t.Helper()
+		checkSequence(t, seedName, calls, func(calls []MethodCall) sequenceRun {
+			return m.run(set, calls)
+		})
+	})
+}
+
+// Add adds the sequence of calls to the corpus of f. Pass the options passed
+// to Fuzz.
+func (m Methods[I]) Add(f TestingF, calls []MethodCall, opts ...Option) {
+	set, err := m.decodingSet(newConfig(opts).gens)
+	if err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	steps := make([]reflect.Value, 0, len(calls))
+	for _, call := range calls {
+		args, err := set.args(call)
+		if err != nil {
+			f.Fatalf("fuzzing: %v", err)
+			return
+		}
+		steps = append(steps, args)
+	}
+	addSequence(f, set.codec, steps)
+}
+
+// Run runs the sequence of calls, and reports a failure to t like Fuzz does.
+// Use it to turn a reported sequence into a regression test.
+func (m Methods[I]) Run(t TestingT, calls ...MethodCall) {
+	t.Helper()
+	set, err := m.methodSet(nil)
+	if err != nil {
+		t.Fatalf("fuzzing: %v", err)
+		return
+	}
+	for _, call := range calls {
+		if _, err := set.args(call); err != nil {
+			t.Fatalf("fuzzing: %v", err)
+			return
+		}
+	}
+	checkSequence(t, "", calls, func(calls []MethodCall) sequenceRun {
+		return m.run(set, calls)
+	})
+}
+
+// call converts the decoded arguments of a method to the call.
+func (s *methodSet) call(args reflect.Value) MethodCall {
+	method := s.methods[s.byArgs[args.Type()]]
+	call := MethodCall{Method: method.name, Args: []any{}}
+	for i := 0; i < method.t.NumIn(); i++ {
+		call.Args = append(call.Args, args.Field(i).Interface())
+	}
+	return call
+}
+
+// args converts call to the arguments of its method, or returns an error if
+// they do not match the parameters of the method.
+func (s *methodSet) args(call MethodCall) (reflect.Value, error) {
+	i, ok := s.byName[call.Method]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("no method %s to call", call.Method)
+	}
+	method := s.methods[i]
+	if len(call.Args) != method.t.NumIn() {
+		return reflect.Value{}, fmt.Errorf("%s takes %d arguments, got %d", call.Method, method.t.NumIn(), len(call.Args))
+	}
+	args := reflect.New(method.args).Elem()
+	for j, arg := range call.Args {
+		if arg == nil {
+			continue
+		}
+		argValue := reflect.ValueOf(arg)
+		if !argValue.Type().AssignableTo(method.t.In(j)) {
+			return reflect.Value{}, fmt.Errorf("argument %d of %s must be %v, got %T", j, call.Method, method.t.In(j), arg)
+		}
+		args.Field(j).Set(argValue)
+	}
+	return args, nil
+}
+
+// run makes calls on a new implementation. The calls must match the methods.
+func (m Methods[I]) run(set *methodSet, calls []MethodCall) sequenceRun {
+	impl := m.New()
+	implValue := reflect.ValueOf(&impl).Elem()
+	outcome := sequenceRun{failed: -1}
+	for i, call := range calls {
+		result, failure := m.runCall(set, implValue, impl, call)
+		outcome.results = append(outcome.results, result)
+		if failure != "" {
+			outcome.failed = i
+			outcome.failure = failure
+			return outcome
+		}
+	}
+	return outcome
+}
+
+func (m Methods[I]) runCall(set *methodSet, implValue reflect.Value, impl I, call MethodCall) (result string, failure string) {
+	defer recoverStep(&failure)
+	method := set.methods[set.byName[call.Method]]
+	args, _ := set.args(call)
+	in := []reflect.Value{}
+	for i := 0; i < method.t.NumIn(); i++ {
+		in = append(in, args.Field(i))
+	}
+	var out []reflect.Value
+	if method.t.IsVariadic() {
+		out = implValue.Method(method.index).CallSlice(in)
+	} else {
+		out = implValue.Method(method.index).Call(in)
+	}
+	result = renderResults(out)
+	if method.postcondition.IsValid() {
+		errValue := method.postcondition.Call(append(in, out...))[0]
+		if !errValue.IsNil() {
+			return result, fmt.Sprintf("postcondition of %s: %v", call.Method, errValue.Interface())
+		}
+	}
+	return result, checkInvariant(m.Invariant, impl)
+}
+
+// renderResults renders the results of a method call as Go code.
+func renderResults(out []reflect.Value) string {
+	results := make([]string, 0, len(out))
+	for _, value := range out {
+		results = append(results, renderResult(value.Interface()))
+	}
+	if len(results) == 1 {
+		return results[0]
+	}
+	if len(results) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(results, ", ") + ")"
+}
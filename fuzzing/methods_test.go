@@ -0,0 +1,204 @@
+package fuzzing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"strings"
+	"testing"
+)
+
+type methodsCounter interface {
+	Add(n int) int
+	Get() (int, error)
+	Reset()
+}
+
+// methodsCounterImpl only returns an error for totals below -10, and panics
+// when adding 13.
+type methodsCounterImpl struct {
+	total int
+}
+
+func (c *methodsCounterImpl) Add(n int) int {
+	if n == 13 {
+		panic("unlucky")
+	}
+	c.total += n
+	return c.total
+}
+
+func (c *methodsCounterImpl) Get() (int, error) {
+	if c.total < -10 {
+		return 0, errors.New("negative")
+	}
+	return c.total, nil
+}
+
+func (c *methodsCounterImpl) Reset() {
+	c.total = 0
+}
+
+var counterMethods = Methods[methodsCounter]{
+	New: func() methodsCounter { return &methodsCounterImpl{} },
+	Postconditions: map[string]any{
+		"Get": func(total int, err error) error {
+			if err == nil && total < 0 {
+				return fmt.Errorf("got %d without an error", total)
+			}
+			return nil
+		},
+	},
+}
+
+func TestMethods_Run(t *testing.T) {
+	set, err := counterMethods.methodSet(nil)
+	require.NoError(t, err)
+	assert.Len(t, set.methods, 3)
+
+	r := &fakeReporter{}
+	check := func(calls ...MethodCall) {
+		checkSequence(r, "", calls, func(calls []MethodCall) sequenceRun {
+			return counterMethods.run(set, calls)
+		})
+	}
+	check(Call("Add", 1), Call("Get"), Call("Reset"))
+	assert.Empty(t, r.errors)
+
+	check(Call("Add", 1), Call("Reset"), Call("Add", -5), Call("Get"), Call("Add", 2))
+	require.Len(t, r.errors, 1)
+	expected := "step 1 failed: postcondition of Get: got -5 without an error" +
+		"\nshortest failing sequence, 2 of 5 decoded steps:" +
+		"\n\t0: fuzzing.Call(\"Add\", -5) = -5" +
+		"\n\t1: fuzzing.Call(\"Get\") = (-5, nil)" +
+		"\nas Go code:" +
+		"\n\tmachine.Run(t, fuzzing.Call(\"Add\", -5), fuzzing.Call(\"Get\"))"
+	assert.Equal(t, expected, r.errors[0])
+
+	check(Call("Reset"), Call("Add", 13))
+	require.Len(t, r.errors, 2)
+	assert.True(t, strings.HasPrefix(r.errors[1], "step 0 failed: panic: unlucky\n"), r.errors[1])
+}
+
+func TestMethods_Fuzz(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	var seed []byte
+	mockF.EXPECT().Add(gomock.Any()).Do(func(args ...any) {
+		seed = args[0].([]byte)
+	})
+	counterMethods.Add(mockF, []MethodCall{Call("Add", 3), Call("Reset"), Call("Add", 4), Call("Get")})
+
+	var target func(*testing.T, []byte)
+	mockF.EXPECT().Fuzz(gomock.Any()).Do(func(fuzzTarget any) {
+		target = fuzzTarget.(func(*testing.T, []byte))
+	})
+	var last *methodsCounterImpl
+	methods := counterMethods
+	methods.New = func() methodsCounter {
+		last = &methodsCounterImpl{}
+		return last
+	}
+	methods.Fuzz(mockF)
+
+	target(t, seed)
+	assert.Equal(t, &methodsCounterImpl{total: 4}, last)
+
+	set, err := counterMethods.methodSet(nil)
+	require.NoError(t, err)
+	steps, _ := set.codec.decode(seed)
+	calls := []MethodCall{}
+	for _, step := range steps {
+		calls = append(calls, set.call(step))
+	}
+	assert.Equal(t, []MethodCall{
+		{Method: "Add", Args: []any{3}},
+		{Method: "Reset", Args: []any{}},
+		{Method: "Add", Args: []any{4}},
+		{Method: "Get", Args: []any{}},
+	}, calls)
+}
+
+type methodsStore interface {
+	Put(key string, tags []string) error
+	Len() int
+}
+
+func TestMethods_UndecodedArgument(t *testing.T) {
+	methods := Methods[methodsStore]{New: func() methodsStore { return nil }}
+	_, err := methods.decodingSet(nil)
+	assert.EqualError(t, err, `argument 1 of Put is []string, which is never decoded, bind a generator to it with WithFieldGen("Arg1", ...)`)
+
+	gens := newConfig([]Option{WithFieldGen("Arg1", SliceOf(Const("tag"), 2))}).gens
+	set, err := methods.decodingSet(gens)
+	require.NoError(t, err)
+	steps, _ := set.codec.decode([]byte{1, 1, 0, 1, 1, 0})
+	require.Len(t, steps, 1)
+	assert.Equal(t, Call("Put", "", []string{"tag"}), set.call(steps[0]))
+
+	gens = newConfig([]Option{WithGen(SliceOf(Const("tag"), 2))}).gens
+	_, err = methods.decodingSet(gens)
+	require.NoError(t, err)
+}
+
+type methodsCache interface {
+	Get(ctx context.Context, id string) string
+}
+
+type methodsCacheImpl struct{}
+
+func (methodsCacheImpl) Get(ctx context.Context, id string) string {
+	return id
+}
+
+func TestMethods_RunUndecodedArgument(t *testing.T) {
+	methods := Methods[methodsCache]{
+		New: func() methodsCache { return methodsCacheImpl{} },
+		Postconditions: map[string]any{
+			"Get": func(ctx context.Context, id string, got string) error {
+				if ctx == nil || got != id {
+					return fmt.Errorf("got %q for %q", got, id)
+				}
+				return nil
+			},
+		},
+	}
+	// Run is given the arguments, so it needs no generator for the context.
+	methods.Run(t, Call("Get", context.Background(), "x"))
+
+	_, err := methods.decodingSet(nil)
+	assert.EqualError(t, err, `argument 0 of Get is context.Context, which is never decoded, bind a generator to it with WithFieldGen("Arg0", ...)`)
+}
+
+func TestMethods_Invalid(t *testing.T) {
+	_, err := Methods[*methodsCounterImpl]{New: func() *methodsCounterImpl { return nil }}.methodSet(nil)
+	assert.EqualError(t, err, "Methods needs an interface type, got *fuzzing.methodsCounterImpl")
+	_, err = Methods[methodsCounter]{}.methodSet(nil)
+	assert.EqualError(t, err, "Methods.New is nil")
+	_, err = Methods[any]{New: func() any { return nil }}.methodSet(nil)
+	assert.EqualError(t, err, "interface {} has no exported methods")
+
+	methods := counterMethods
+	methods.Postconditions = map[string]any{"Sub": func() error { return nil }}
+	_, err = methods.methodSet(nil)
+	assert.EqualError(t, err, "postcondition of Sub, which is not a method of fuzzing.methodsCounter")
+	methods.Postconditions = map[string]any{"Add": func(n int) error { return nil }}
+	_, err = methods.methodSet(nil)
+	assert.EqualError(t, err, "postcondition of Add: got func(int) error, want func(int, int) error")
+
+	set, err := counterMethods.methodSet(nil)
+	require.NoError(t, err)
+	_, err = set.args(Call("Sub", 1))
+	assert.EqualError(t, err, "no method Sub to call")
+	_, err = set.args(Call("Add"))
+	assert.EqualError(t, err, "Add takes 1 arguments, got 0")
+	_, err = set.args(Call("Add", "1"))
+	assert.EqualError(t, err, "argument 0 of Add must be int, got string")
+}
@@ -0,0 +1,122 @@
+package fuzzing
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ModelOp is an operation of a model-based test, applied both to the system
+// under test S and to a reference model M of it, such as a map-backed store.
+// Apply and Model return what is observable about the operation, such as
+// the value read and whether an error was returned, and the step fails if they
+// differ according to reflect.DeepEqual. The exported fields of the type
+// implementing ModelOp are its arguments, as for Op.
+type ModelOp[S any, M any] interface {
+	Apply(s S) any
+	Model(m M) any
+}
+
+// ModelMachine fuzzes sequences of operations against both a system under
+// test and a reference model, and fails at the first step at which their
+// results differ. Like for Machine, a failing sequence is shrunk before it is
+// reported, along with what every step returned.
+//
+//	fuzzing.ModelMachine[*Store, map[string]string]{
+//		New:      OpenTestStore,
+//		NewModel: func() map[string]string { return map[string]string{} },
+//		Ops:      []fuzzing.ModelOp[*Store, map[string]string]{Put{}, Get{}, Delete{}},
+//	}.Fuzz(f)
+type ModelMachine[S any, M any] struct {
+	// New returns a new system under test, for every sequence.
+	New func() S
+	// NewModel returns a new model, for every sequence.
+	NewModel func() M
+	// Ops are a value of every type of operation. Only the types matter, the
+	// values of their fields are ignored.
+	Ops []ModelOp[S, M]
+	// Invariant, if set, is checked on the system under test after every step.
+	Invariant func(s S) error
+	// MaxSteps is the maximum number of steps in a sequence, 32 if zero.
+	MaxSteps int
+}
+
+// codec returns the codec of the sequences of m.
+func (m ModelMachine[S, M]) codec(gens genBindings) (*sequenceCodec, error) {
+	if m.New == nil {
+		return nil, fmt.Errorf("ModelMachine.New is nil")
+	}
+	if m.NewModel == nil {
+		return nil, fmt.Errorf("ModelMachine.NewModel is nil")
+	}
+	return newOpsCodec(m.Ops, m.MaxSteps, gens)
+}
+
+// Fuzz fuzzes sequences of the operations of m, like Machine.Fuzz.
+func (m ModelMachine[S, M]) Fuzz(f TestingF, opts ...Option) {
+	cfg := newConfig(opts)
+	codec, err := m.codec(cfg.gens)
+	if err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	fuzzSequence(f, cfg, codec, stepAs[ModelOp[S, M]], m.check)
+}
+
+// Add adds the sequence ops to the corpus of f. Pass the options passed to
+// Fuzz.
+func (m ModelMachine[S, M]) Add(f TestingF, ops []ModelOp[S, M], opts ...Option) {
+	codec, err := m.codec(newConfig(opts).gens)
+	if err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	addSequence(f, codec, stepValues(ops))
+}
+
+// Run runs the sequence ops, and reports a failure to t like Fuzz does. Use it
+// to turn a reported sequence into a regression test.
+func (m ModelMachine[S, M]) Run(t TestingT, ops ...ModelOp[S, M]) {
+	t.Helper()
+	m.check(t, "", ops)
+}
+
+func (m ModelMachine[S, M]) check(t failureReporter, seedName string, ops []ModelOp[S, M]) {
+	t.Helper()
+	checkSequence(t, seedName, ops, m.run)
+}
+
+// run applies ops to a new system under test and a new model.
+func (m ModelMachine[S, M]) run(ops []ModelOp[S, M]) sequenceRun {
+	s := m.New()
+	model := m.NewModel()
+	outcome := sequenceRun{failed: -1}
+	for i, op := range ops {
+		result, failure := m.runStep(s, model, op)
+		outcome.results = append(outcome.results, result)
+		if failure != "" {
+			outcome.failed = i
+			outcome.failure = failure
+			return outcome
+		}
+	}
+	return outcome
+}
+
+func (m ModelMachine[S, M]) runStep(s S, model M, op ModelOp[S, M]) (result string, failure string) {
+	defer recoverStep(&failure)
+	actual := op.Apply(s)
+	result = renderResult(actual)
+	expected := op.Model(model)
+	if !reflect.DeepEqual(actual, expected) {
+		return result, fmt.Sprintf("returned %s, but the model returned %s", result, renderResult(expected))
+	}
+	return result, checkInvariant(m.Invariant, s)
+}
+
+// renderResult renders a result of a ModelOp as Go code.
+func renderResult(v any) string {
+	if err, ok := v.(error); ok {
+		return fmt.Sprintf("errors.New(%q)", err.Error())
+	}
+	return GoLiteral(v)
+}
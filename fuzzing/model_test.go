@@ -0,0 +1,155 @@
+package fuzzing
+
+import (
+	"errors"
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+// modelStore appends every put, and finds the first, rather than the last,
+// put of a key.
+type modelStore struct {
+	keys []string
+	vals []int
+}
+
+type modelGetResult struct {
+	Val int
+	OK  bool
+}
+
+type modelPut struct {
+	Key string
+	Val int
+}
+
+func (op modelPut) Apply(s *modelStore) any {
+	s.keys = append(s.keys, op.Key)
+	s.vals = append(s.vals, op.Val)
+	return nil
+}
+
+func (op modelPut) Model(m map[string]int) any {
+	m[op.Key] = op.Val
+	return nil
+}
+
+type modelGet struct {
+	Key string
+}
+
+func (op modelGet) Apply(s *modelStore) any {
+	for i, key := range s.keys {
+		if key == op.Key {
+			return modelGetResult{Val: s.vals[i], OK: true}
+		}
+	}
+	return modelGetResult{}
+}
+
+func (op modelGet) Model(m map[string]int) any {
+	val, ok := m[op.Key]
+	return modelGetResult{Val: val, OK: ok}
+}
+
+type modelCheck struct{}
+
+func (op modelCheck) Apply(s *modelStore) any {
+	return errors.New("check failed")
+}
+
+func (op modelCheck) Model(m map[string]int) any {
+	return errors.New("check failed")
+}
+
+var storeModelMachine = ModelMachine[*modelStore, map[string]int]{
+	New:      func() *modelStore { return &modelStore{} },
+	NewModel: func() map[string]int { return map[string]int{} },
+	Ops:      []ModelOp[*modelStore, map[string]int]{modelPut{}, modelGet{}, modelCheck{}},
+}
+
+func TestModelMachine_Run(t *testing.T) {
+	r := &fakeReporter{}
+	storeModelMachine.check(r, "", []ModelOp[*modelStore, map[string]int]{
+		modelPut{Key: "a", Val: 1},
+		modelGet{Key: "a"},
+		modelGet{Key: "b"},
+		modelCheck{},
+	})
+	assert.Empty(t, r.errors)
+
+	storeModelMachine.check(r, "", []ModelOp[*modelStore, map[string]int]{
+		modelGet{Key: "b"},
+		modelPut{Key: "a", Val: 1},
+		modelCheck{},
+		modelPut{Key: "a", Val: 2},
+		modelGet{Key: "a"},
+		modelPut{Key: "c", Val: 3},
+	})
+	require.Len(t, r.errors, 1)
+	expected := "step 2 failed: returned modelGetResult{Val: 1, OK: true}, but the model returned modelGetResult{Val: 2, OK: true}" +
+		"\nshortest failing sequence, 3 of 6 decoded steps:" +
+		"\n\t0: modelPut{Key: \"a\", Val: 1} = nil" +
+		"\n\t1: modelPut{Key: \"a\", Val: 2} = nil" +
+		"\n\t2: modelGet{Key: \"a\"} = modelGetResult{Val: 1, OK: true}" +
+		"\nas Go code:" +
+		"\n\tmachine.Run(t, modelPut{Key: \"a\", Val: 1}, modelPut{Key: \"a\", Val: 2}, modelGet{Key: \"a\"})"
+	assert.Equal(t, expected, r.errors[0])
+}
+
+func TestModelMachine_Fuzz(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	var seed []byte
+	mockF.EXPECT().Add(gomock.Any()).Do(func(args ...any) {
+		seed = args[0].([]byte)
+	})
+	storeModelMachine.Add(mockF, []ModelOp[*modelStore, map[string]int]{modelPut{Key: "a", Val: 1}, modelGet{Key: "a"}})
+
+	var target func(*testing.T, []byte)
+	mockF.EXPECT().Fuzz(gomock.Any()).Do(func(fuzzTarget any) {
+		target = fuzzTarget.(func(*testing.T, []byte))
+	})
+	var last *modelStore
+	machine := storeModelMachine
+	machine.New = func() *modelStore {
+		last = &modelStore{}
+		return last
+	}
+	machine.Fuzz(mockF)
+
+	target(t, seed)
+	assert.Equal(t, &modelStore{keys: []string{"a"}, vals: []int{1}}, last)
+}
+
+func TestModelMachine_Invalid(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	mockF.EXPECT().Fatalf("fuzzing: %v", gomock.Any()).Do(func(format string, args ...any) {
+		assert.EqualError(t, args[0].(error), "ModelMachine.NewModel is nil")
+	})
+	machine := storeModelMachine
+	machine.NewModel = nil
+	machine.Fuzz(mockF)
+
+	c, err := NewConstructor[*ctorAccount](newCtorAccount)
+	require.NoError(t, err)
+	mockF.EXPECT().Fatalf("fuzzing: %v", gomock.Any()).Do(func(format string, args ...any) {
+		assert.EqualError(t, args[0].(error), "stateful tests do not support WithConstructor, only generators and AllowNonCanonicalInputs")
+	})
+	storeModelMachine.Fuzz(mockF, WithConstructor(c))
+}
+
+func TestRenderResult(t *testing.T) {
+	assert.Equal(t, "nil", renderResult(nil))
+	assert.Equal(t, `errors.New("oops")`, renderResult(errors.New("oops")))
+	assert.Equal(t, "42", renderResult(42))
+}
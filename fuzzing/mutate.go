@@ -0,0 +1,546 @@
+package fuzzing
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"reflect"
+)
+
+// maxMutatedLen is the length beyond which a Mutator stops growing strings,
+// byte slices and slices.
+const maxMutatedLen = 1 << 12
+
+// Mutator makes random structural changes to values of T, for fuzzing loops
+// that work on typed values instead of raw bytes, such as the runner package.
+// It flips bools and pointer presence, tweaks numbers, adds, removes and swaps
+// slice elements and map entries, edits strings and byte slices, and splices
+// fields between values. Unexported fields and values behind interfaces,
+// channels and funcs are left alone. Mutators registered with
+// WithTypeMutator and WithFieldMutator are picked for half of the changes, if
+// they apply to the value. A Mutator is not safe for concurrent use.
+type Mutator[T any] struct {
+	rand   *rand.Rand
+	custom []customMutator
+}
+
+// MutatorOption configures a Mutator.
+type MutatorOption func(*mutatorConfig)
+
+type mutatorConfig struct {
+	custom []customMutator
+}
+
+// customMutator is a user-defined mutator for the values of a type, or for the
+// value at a field path.
+type customMutator struct {
+	t reflect.Type
+	// path is the field path the mutator applies to, unless anyPath is set.
+	path    string
+	anyPath bool
+	fn      func(reflect.Value, *rand.Rand)
+}
+
+func (c customMutator) appliesTo(site mutationSite) bool {
+	return site.value.Type() == c.t && (c.anyPath || site.path == c.path)
+}
+
+// WithTypeMutator registers fn to change values of type V in place, wherever
+// they occur in the mutated value.
+func WithTypeMutator[V any](fn func(v *V, r *rand.Rand)) MutatorOption {
+	return func(c *mutatorConfig) {
+		c.custom = append(c.custom, customMutator{
+			t:       reflect.TypeFor[V](),
+			anyPath: true,
+			fn: func(value reflect.Value, r *rand.Rand) {
+				fn(value.Addr().Interface().(*V), r)
+			},
+		})
+	}
+}
+
+// WithFieldMutator registers fn to change the value at the field path path in
+// place, such as "Order.Amount". The elements of slices and arrays are matched
+// by "[]", as in "Orders[].Amount". The path "" is the mutated value itself.
+func WithFieldMutator[V any](path string, fn func(v *V, r *rand.Rand)) MutatorOption {
+	return func(c *mutatorConfig) {
+		c.custom = append(c.custom, customMutator{
+			t:    reflect.TypeFor[V](),
+			path: path,
+			fn: func(value reflect.Value, r *rand.Rand) {
+				fn(value.Addr().Interface().(*V), r)
+			},
+		})
+	}
+}
+
+// NewMutator returns a Mutator that draws its random choices from r. It panics
+// if a mutator registered with opts can never apply to values of T.
+func NewMutator[T any](r *rand.Rand, opts ...MutatorOption) *Mutator[T] {
+	m, err := newMutator[T](r, opts)
+	if err != nil {
+		panic(fmt.Errorf("fuzzing: %w", err))
+	}
+	return m
+}
+
+// CheckMutators returns an error if a mutator registered with opts can never
+// apply to values of T, which NewMutator panics for.
+func CheckMutators[T any](opts ...MutatorOption) error {
+	_, err := newMutator[T](nil, opts)
+	return err
+}
+
+func newMutator[T any](r *rand.Rand, opts []MutatorOption) (*Mutator[T], error) {
+	c := &mutatorConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	paths := map[string]reflect.Type{}
+	mutationPaths(reflect.TypeFor[T](), "", map[reflect.Type]bool{}, paths)
+	for _, custom := range c.custom {
+		if !custom.appliesToAny(paths) {
+			if custom.anyPath {
+				return nil, fmt.Errorf("no value of type %v in %v", custom.t, reflect.TypeFor[T]())
+			}
+			return nil, fmt.Errorf("no value of type %v at field path %q in %v",
+				custom.t, custom.path, reflect.TypeFor[T]())
+		}
+	}
+	return &Mutator[T]{rand: r, custom: c.custom}, nil
+}
+
+func (c customMutator) appliesToAny(paths map[string]reflect.Type) bool {
+	for path, t := range paths {
+		if t == c.t && (c.anyPath || path == c.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// mutationPaths adds the field path and type of every value that
+// mutationSites can find in a value of type t, at field path path, to paths.
+func mutationPaths(t reflect.Type, path string, onStack map[reflect.Type]bool, paths map[string]reflect.Type) {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return
+	}
+	paths[path] = t
+	if onStack[t] {
+		// Stop at recursive types.
+		return
+	}
+	onStack[t] = true
+	defer delete(onStack, t)
+	switch t.Kind() {
+	case reflect.Pointer:
+		mutationPaths(t.Elem(), pointeeFieldPath(path, t), onStack, paths)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				mutationPaths(t.Field(i).Type, joinFieldPath(path, t.Field(i).Name), onStack, paths)
+			}
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		fallthrough
+	case reflect.Array:
+		mutationPaths(t.Elem(), path+"[]", onStack, paths)
+	}
+}
+
+// mutationSite is a value within the value being mutated.
+type mutationSite struct {
+	// path is the field path of the value, with "[]" for the elements of
+	// slices and arrays.
+	path  string
+	value reflect.Value
+}
+
+// Mutate returns a copy of v with one random change. v itself is not
+// modified. v is returned as is if nothing in it can be changed.
+func (m *Mutator[T]) Mutate(v T) T {
+	value := deepCopy(reflect.ValueOf(&v).Elem())
+	sites := mutationSites(value, "", nil)
+	if m.mutateCustom(sites) {
+		return value.Interface().(T)
+	}
+	// Not every site can be changed, such as structs and fixed size arrays
+	// of one element, so try a few.
+	for attempt := 0; attempt < 8 && len(sites) > 0; attempt++ {
+		if m.mutateSite(sites[m.rand.IntN(len(sites))].value) {
+			return value.Interface().(T)
+		}
+	}
+	return v
+}
+
+// Splice returns a copy of v in which a random field, slice element or
+// pointer is replaced by a copy of the value at the same field path in other.
+// Neither v nor other are modified.
+func (m *Mutator[T]) Splice(v, other T) T {
+	value := deepCopy(reflect.ValueOf(&v).Elem())
+	sites := mutationSites(value, "", nil)
+	otherSites := mutationSites(reflect.ValueOf(&other).Elem(), "", nil)
+	for attempt := 0; attempt < 8 && len(sites) > 0; attempt++ {
+		site := sites[m.rand.IntN(len(sites))]
+		if site.path == "" {
+			// Replacing the root is not a splice.
+			continue
+		}
+		candidates := []reflect.Value{}
+		for _, otherSite := range otherSites {
+			if otherSite.path == site.path && otherSite.value.Type() == site.value.Type() {
+				candidates = append(candidates, otherSite.value)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		site.value.Set(deepCopy(candidates[m.rand.IntN(len(candidates))]))
+		return value.Interface().(T)
+	}
+	return v
+}
+
+// mutateCustom applies a random user-defined mutator to one of sites, half of
+// the time, and reports whether it did.
+func (m *Mutator[T]) mutateCustom(sites []mutationSite) bool {
+	type candidate struct {
+		site   mutationSite
+		custom customMutator
+	}
+	candidates := []candidate{}
+	for _, site := range sites {
+		for _, custom := range m.custom {
+			if custom.appliesTo(site) {
+				candidates = append(candidates, candidate{site, custom})
+			}
+		}
+	}
+	if len(candidates) == 0 || m.rand.IntN(2) == 0 {
+		return false
+	}
+	chosen := candidates[m.rand.IntN(len(candidates))]
+	chosen.custom.fn(chosen.site.value, m.rand)
+	return true
+}
+
+// deepCopy returns an addressable copy of value that shares no slices, maps
+// or pointers with it, apart from those in unexported fields and interfaces.
+func deepCopy(value reflect.Value) reflect.Value {
+	t := value.Type()
+	c := reflect.New(t).Elem()
+	switch value.Kind() {
+	case reflect.Pointer:
+		if !value.IsNil() {
+			pointer := reflect.New(t.Elem())
+			pointer.Elem().Set(deepCopy(value.Elem()))
+			c.Set(pointer)
+		}
+	case reflect.Struct:
+		c.Set(value)
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				c.Field(i).Set(deepCopy(value.Field(i)))
+			}
+		}
+	case reflect.Slice:
+		if !value.IsNil() {
+			slice := reflect.MakeSlice(t, value.Len(), value.Len())
+			for i := 0; i < value.Len(); i++ {
+				slice.Index(i).Set(deepCopy(value.Index(i)))
+			}
+			c.Set(slice)
+		}
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			c.Index(i).Set(deepCopy(value.Index(i)))
+		}
+	case reflect.Map:
+		if !value.IsNil() {
+			mapValue := reflect.MakeMapWithSize(t, value.Len())
+			iter := value.MapRange()
+			for iter.Next() {
+				mapValue.SetMapIndex(deepCopy(iter.Key()), deepCopy(iter.Value()))
+			}
+			c.Set(mapValue)
+		}
+	default:
+		c.Set(value)
+	}
+	return c
+}
+
+// mutationSites appends value, at field path path, and every value reachable
+// from it that a Mutator can change, to sites.
+func mutationSites(value reflect.Value, path string, sites []mutationSite) []mutationSite {
+	switch value.Kind() {
+	case reflect.Interface, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return sites
+	}
+	sites = append(sites, mutationSite{path: path, value: value})
+	switch value.Kind() {
+	case reflect.Pointer:
+		if !value.IsNil() {
+			sites = mutationSites(value.Elem(), pointeeFieldPath(path, value.Type()), sites)
+		}
+	case reflect.Struct:
+		t := value.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				sites = mutationSites(value.Field(i), joinFieldPath(path, t.Field(i).Name), sites)
+			}
+		}
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			// Byte slices are edited like strings.
+			break
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			sites = mutationSites(value.Index(i), path+"[]", sites)
+		}
+	}
+	return sites
+}
+
+// mutateSite makes one random change to value, and reports whether it did.
+func (m *Mutator[T]) mutateSite(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Bool:
+		value.SetBool(!value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch m.rand.IntN(3) {
+		case 0:
+			value.SetInt(value.Int() + m.delta())
+		case 1:
+			value.Set(m.boundaryValue(value.Type()))
+		default:
+			value.SetInt(int64(m.rand.Uint64()))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch m.rand.IntN(3) {
+		case 0:
+			value.SetUint(value.Uint() + uint64(m.delta()))
+		case 1:
+			value.Set(m.boundaryValue(value.Type()))
+		default:
+			value.SetUint(m.rand.Uint64())
+		}
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat(m.mutateFloat(value.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		c := value.Complex()
+		if m.rand.IntN(2) == 0 {
+			value.SetComplex(complex(m.mutateFloat(real(c)), imag(c)))
+		} else {
+			value.SetComplex(complex(real(c), m.mutateFloat(imag(c))))
+		}
+	case reflect.String:
+		value.SetString(string(m.mutateBytes([]byte(value.String()))))
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			value.SetBytes(m.mutateBytes(value.Bytes()))
+			return true
+		}
+		return m.mutateSlice(value)
+	case reflect.Array:
+		if value.Len() < 2 {
+			return false
+		}
+		i, j := m.rand.IntN(value.Len()), m.rand.IntN(value.Len())
+		swapped := deepCopy(value.Index(i))
+		value.Index(i).Set(value.Index(j))
+		value.Index(j).Set(swapped)
+	case reflect.Map:
+		return m.mutateMap(value)
+	case reflect.Pointer:
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		} else {
+			value.Set(reflect.Zero(value.Type()))
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// delta returns a small non-zero number.
+func (m *Mutator[T]) delta() int64 {
+	d := int64(m.rand.IntN(16) + 1)
+	if m.rand.IntN(2) == 0 {
+		return -d
+	}
+	return d
+}
+
+// boundaryValue returns zero or one of the boundary values of t.
+func (m *Mutator[T]) boundaryValue(t reflect.Type) reflect.Value {
+	values := boundaryValues(t)
+	i := m.rand.IntN(len(values) + 1)
+	if i == len(values) {
+		return reflect.Zero(t)
+	}
+	return reflect.ValueOf(values[i]).Convert(t)
+}
+
+func (m *Mutator[T]) mutateFloat(f float64) float64 {
+	switch m.rand.IntN(5) {
+	case 0:
+		return f + float64(m.delta())
+	case 1:
+		return f * float64(m.delta())
+	case 2:
+		return -f
+	case 3:
+		return m.boundaryValue(reflect.TypeFor[float64]()).Float()
+	default:
+		return math.Float64frombits(m.rand.Uint64())
+	}
+}
+
+// mutateBytes returns b with one random edit. b itself is not modified.
+func (m *Mutator[T]) mutateBytes(b []byte) []byte {
+	b = append([]byte{}, b...)
+	op := m.rand.IntN(6)
+	if len(b) == 0 {
+		// Only growing is possible.
+		op = 0
+	} else if len(b) >= maxMutatedLen && (op == 0 || op == 4) {
+		op = 1
+	}
+	switch op {
+	case 0:
+		i := m.rand.IntN(len(b) + 1)
+		return append(b[:i], append([]byte{byte(m.rand.Uint32())}, b[i:]...)...)
+	case 1:
+		i := m.rand.IntN(len(b))
+		j := i + 1 + m.rand.IntN(len(b)-i)
+		return append(b[:i], b[j:]...)
+	case 2:
+		b[m.rand.IntN(len(b))] = byte(m.rand.Uint32())
+	case 3:
+		b[m.rand.IntN(len(b))] ^= 1 << m.rand.IntN(8)
+	case 4:
+		i := m.rand.IntN(len(b))
+		j := i + 1 + m.rand.IntN(len(b)-i)
+		chunk := append([]byte{}, b[i:j]...)
+		k := m.rand.IntN(len(b) + 1)
+		return append(b[:k], append(chunk, b[k:]...)...)
+	default:
+		return b[:m.rand.IntN(len(b))]
+	}
+	return b
+}
+
+// mutateSlice adds, removes, duplicates or swaps elements of value.
+func (m *Mutator[T]) mutateSlice(value reflect.Value) bool {
+	n := value.Len()
+	op := m.rand.IntN(4)
+	if n == 0 {
+		op = 0
+	} else if n >= maxMutatedLen && (op == 0 || op == 2) {
+		op = 1
+	}
+	switch op {
+	case 0:
+		// Add a zero element.
+		value.Set(insertElement(value, m.rand.IntN(n+1), reflect.Zero(value.Type().Elem())))
+	case 1:
+		// Remove an element.
+		i := m.rand.IntN(n)
+		value.Set(reflect.AppendSlice(value.Slice3(0, i, i), value.Slice(i+1, n)))
+	case 2:
+		// Duplicate an element.
+		element := deepCopy(value.Index(m.rand.IntN(n)))
+		value.Set(insertElement(value, m.rand.IntN(n+1), element))
+	default:
+		if n < 2 {
+			return false
+		}
+		i, j := m.rand.IntN(n), m.rand.IntN(n)
+		swapped := deepCopy(value.Index(i))
+		value.Index(i).Set(value.Index(j))
+		value.Index(j).Set(swapped)
+	}
+	return true
+}
+
+// insertElement returns a copy of slice with element inserted at i.
+func insertElement(slice reflect.Value, i int, element reflect.Value) reflect.Value {
+	inserted := reflect.MakeSlice(slice.Type(), 0, slice.Len()+1)
+	inserted = reflect.AppendSlice(inserted, slice.Slice(0, i))
+	inserted = reflect.Append(inserted, element)
+	return reflect.AppendSlice(inserted, slice.Slice(i, slice.Len()))
+}
+
+// mutateMap removes an entry of value, changes the value of an entry, or adds
+// an entry with a mutated key.
+func (m *Mutator[T]) mutateMap(value reflect.Value) bool {
+	t := value.Type()
+	if value.IsNil() {
+		value.Set(reflect.MakeMap(t))
+	}
+	keys := value.MapKeys()
+	op := m.rand.IntN(3)
+	if len(keys) == 0 {
+		op = 0
+	}
+	switch op {
+	case 0:
+		key := reflect.New(t.Key()).Elem()
+		m.mutateSite(key)
+		if value.MapIndex(key).IsValid() {
+			return false
+		}
+		value.SetMapIndex(key, reflect.Zero(t.Elem()))
+	case 1:
+		value.SetMapIndex(keys[m.rand.IntN(len(keys))], reflect.Value{})
+	default:
+		// Map values are not addressable, so mutate a copy.
+		key := keys[m.rand.IntN(len(keys))]
+		element := deepCopy(value.MapIndex(key))
+		sites := mutationSites(element, "", nil)
+		if len(sites) == 0 || !m.mutateSite(sites[m.rand.IntN(len(sites))].value) {
+			return false
+		}
+		value.SetMapIndex(key, element)
+	}
+	return true
+}
+
+// AddMutated adds n variants of seed to the corpus, as if by AddNamed, each
+// with a few random changes made by a Mutator with mutators. Use it to turn
+// domain knowledge, captured in user-defined mutators, into seeds for the Go
+// engine. The variants are the same on every run, so that seed#N keeps naming
+// the same input. Duplicate variants are only added once. Pass the options
+// passed to Fuzz as opts, as generators change the layout.
+func AddMutated[T any](f TestingF, seed T, n int, mutators []MutatorOption, opts ...Option) {
+	cfg := newConfig(opts)
+	if err := cfg.checkSeedType(reflect.TypeFor[T]()); err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	mutator := NewMutator[T](rand.New(rand.NewPCG(uint64(n), 0)), mutators...)
+	seen := map[string]bool{}
+	for i := 0; i < n; i++ {
+		v := seed
+		for changes := 1 + mutator.rand.IntN(3); changes > 0; changes-- {
+			v = mutator.Mutate(v)
+		}
+		// cfg applies to T, as checked above.
+		fields, _ := flatten(v, cfg)
+		key := fmt.Sprintf("%#v", fields)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		addFields(f, fmt.Sprintf("mutation %d", i+1), fields)
+	}
+}
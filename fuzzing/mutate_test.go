@@ -0,0 +1,191 @@
+package fuzzing
+
+import (
+	"fmt"
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+type mutateItem struct {
+	N int
+}
+
+type mutateFoo struct {
+	S     string
+	B     []byte
+	P     *int
+	Items []mutateItem
+	M     map[string]int
+	A     [2]uint8
+	i     int
+}
+
+func TestMutator_Mutate(t *testing.T) {
+	mutator := NewMutator[mutateFoo](rand.New(rand.NewPCG(1, 2)))
+	original := mutateFoo{
+		S:     "foo",
+		B:     []byte("bar"),
+		P:     ptr(1),
+		Items: []mutateItem{{N: 1}, {N: 2}},
+		M:     map[string]int{"a": 1},
+		i:     7,
+	}
+	seen := map[string]bool{}
+	v := original
+	for i := 0; i < 2000; i++ {
+		v = mutator.Mutate(v)
+		assert.Equal(t, 7, v.i)
+		seen[GoLiteral(v)] = true
+	}
+	// The original value is never modified.
+	assert.Equal(t, mutateFoo{
+		S:     "foo",
+		B:     []byte("bar"),
+		P:     ptr(1),
+		Items: []mutateItem{{N: 1}, {N: 2}},
+		M:     map[string]int{"a": 1},
+		i:     7,
+	}, original)
+	assert.Greater(t, len(seen), 1000)
+}
+
+func TestMutator_MutateReachesEverySite(t *testing.T) {
+	mutator := NewMutator[mutateFoo](rand.New(rand.NewPCG(3, 4)))
+	changed := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		v := mutator.Mutate(mutateFoo{Items: []mutateItem{{}}})
+		changed["S"] = changed["S"] || v.S != ""
+		changed["B"] = changed["B"] || len(v.B) > 0
+		changed["P"] = changed["P"] || v.P != nil
+		changed["Items"] = changed["Items"] || len(v.Items) != 1
+		changed["Items[].N"] = changed["Items[].N"] || len(v.Items) == 1 && v.Items[0].N != 0
+		changed["M"] = changed["M"] || len(v.M) > 0
+		changed["A"] = changed["A"] || v.A != [2]uint8{}
+	}
+	for _, path := range []string{"S", "B", "P", "Items", "Items[].N", "M", "A"} {
+		assert.True(t, changed[path], path)
+	}
+}
+
+func TestMutator_MutateNothingToChange(t *testing.T) {
+	type empty struct {
+		f func()
+	}
+	mutator := NewMutator[empty](rand.New(rand.NewPCG(1, 2)))
+	assert.Equal(t, empty{}, mutator.Mutate(empty{}))
+}
+
+func TestMutator_Splice(t *testing.T) {
+	mutator := NewMutator[mutateFoo](rand.New(rand.NewPCG(5, 6)))
+	a := mutateFoo{S: "a", P: ptr(1)}
+	b := mutateFoo{S: "b", Items: []mutateItem{{N: 2}}}
+	spliced := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		v := mutator.Splice(a, b)
+		spliced[GoLiteral(v)] = true
+	}
+	assert.True(t, spliced[`mutateFoo{S: "b", P: ptr(1)}`])
+	assert.True(t, spliced[`mutateFoo{S: "a", P: ptr(1), Items: []mutateItem{mutateItem{N: 2}}}`])
+	assert.True(t, spliced[`mutateFoo{S: "a"}`])
+	// Neither value is modified, and no slices are shared.
+	assert.Equal(t, mutateFoo{S: "a", P: ptr(1)}, a)
+	v := mutator.Splice(a, b)
+	for v.Items == nil {
+		v = mutator.Splice(a, b)
+	}
+	v.Items[0].N = 3
+	assert.Equal(t, 2, b.Items[0].N)
+}
+
+type mutateOrder struct {
+	Amount int64
+}
+
+type mutateCart struct {
+	Orders []mutateOrder
+	Note   string
+}
+
+func TestMutator_WithFieldMutator(t *testing.T) {
+	mutator := NewMutator[mutateCart](rand.New(rand.NewPCG(1, 2)),
+		WithFieldMutator("Orders[].Amount", func(amount *int64, r *rand.Rand) {
+			*amount = math.MaxInt64
+		}),
+		WithFieldMutator("Orders", func(orders *[]mutateOrder, r *rand.Rand) {
+			if len(*orders) > 1 {
+				(*orders)[0], (*orders)[1] = (*orders)[1], (*orders)[0]
+			}
+		}),
+	)
+	seed := mutateCart{Orders: []mutateOrder{{Amount: 1}, {Amount: 2}}}
+	overflowed, swapped := false, false
+	for i := 0; i < 100; i++ {
+		v := mutator.Mutate(seed)
+		for _, order := range v.Orders {
+			overflowed = overflowed || order.Amount == math.MaxInt64
+		}
+		swapped = swapped || len(v.Orders) == 2 && v.Orders[0].Amount == 2 && v.Orders[1].Amount == 1
+	}
+	assert.True(t, overflowed)
+	assert.True(t, swapped)
+	assert.Equal(t, int64(1), seed.Orders[0].Amount)
+}
+
+func TestMutator_WithTypeMutator(t *testing.T) {
+	mutator := NewMutator[mutateCart](rand.New(rand.NewPCG(1, 2)),
+		WithTypeMutator(func(s *string, r *rand.Rand) {
+			*s = "header: " + *s
+		}),
+	)
+	found := false
+	for i := 0; i < 100 && !found; i++ {
+		found = mutator.Mutate(mutateCart{Note: "x"}).Note == "header: x"
+	}
+	assert.True(t, found)
+}
+
+func TestNewMutator_InvalidMutator(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	assert.PanicsWithError(t, `fuzzing: no value of type string at field path "Orders[].Amount" in fuzzing.mutateCart`, func() {
+		NewMutator[mutateCart](r, WithFieldMutator("Orders[].Amount", func(s *string, r *rand.Rand) {}))
+	})
+	assert.PanicsWithError(t, `fuzzing: no value of type float64 in fuzzing.mutateCart`, func() {
+		NewMutator[mutateCart](r, WithTypeMutator(func(f *float64, r *rand.Rand) {}))
+	})
+}
+
+func TestCheckMutators(t *testing.T) {
+	assert.NoError(t, CheckMutators[mutateCart](WithTypeMutator(func(s *string, r *rand.Rand) {})))
+	assert.EqualError(t, CheckMutators[mutateCart](WithTypeMutator(func(f *float64, r *rand.Rand) {})),
+		"no value of type float64 in fuzzing.mutateCart")
+}
+
+func TestAddMutated(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	added := [][]any{}
+	mockF.EXPECT().Add(gomock.Any()).Do(func(args ...any) {
+		added = append(added, args)
+	}).AnyTimes()
+	seed := mutateCart{Note: "note"}
+	AddMutated(mockF, seed, 20, []MutatorOption{WithTypeMutator(func(s *string, r *rand.Rand) {
+		*s = "header: " + *s
+	})})
+	assert.NotEmpty(t, added)
+	assert.LessOrEqual(t, len(added), 20)
+	seen := map[string]bool{}
+	for _, args := range added {
+		key := fmt.Sprintf("%#v", args)
+		assert.False(t, seen[key], "duplicate seed %v", args)
+		seen[key] = true
+	}
+	names := takeSeedNames(mockF).names
+	assert.Len(t, names, len(added))
+	assert.Equal(t, "mutation 1", names[0])
+}
@@ -0,0 +1,115 @@
+package fuzzing
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Option configures how Fuzz decodes and runs the fuzz target.
+type Option func(*config)
+
+type config struct {
+	preconditions []func(any) bool
+	boundarySeeds bool
+	// allowNonCanonical disables skipping of non-canonical inputs.
+	allowNonCanonical bool
+	gens              genBindings
+	// constructor builds the value passed to the fuzz target, if set.
+	constructor *constructor
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithPrecondition discards every decoded input for which fn returns false.
+// Discarded inputs are skipped rather than reported as failures.
+func WithPrecondition[T any](fn func(T) bool) Option {
+	return func(c *config) {
+		c.preconditions = append(c.preconditions, func(v any) bool {
+			return fn(v.(T))
+		})
+	}
+}
+
+// AllowNonCanonicalInputs runs the fuzz target for every input while fuzzing.
+//
+// By default, while fuzzing with -fuzz, inputs with a non-zero payload behind
+// a nil pointer are skipped. Such inputs decode to the same value as the input
+// with a zero payload, so running the fuzz target for them wastes effort.
+// Inputs are never skipped when running the seed corpus with plain go test.
+func AllowNonCanonicalInputs() Option {
+	return func(c *config) {
+		c.allowNonCanonical = true
+	}
+}
+
+func (c *config) accepts(v any) bool {
+	for _, precondition := range c.preconditions {
+		if !precondition(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeType returns the type decoded from the input for the fuzzed type
+// tType: tType, or the arguments of the constructor of tType. It returns an
+// error if the options do not apply to tType.
+func (c *config) decodeType(tType reflect.Type) (reflect.Type, error) {
+	decodeType := tType
+	if c.constructor != nil {
+		if c.constructor.result != tType {
+			return nil, fmt.Errorf("constructor %s returns %v, not %v", c.constructor.name, c.constructor.result, tType)
+		}
+		decodeType = c.constructor.args
+	}
+	if err := c.gens.check(decodeType); err != nil {
+		return nil, err
+	}
+	return decodeType, nil
+}
+
+// checkSeedType returns an error if the options do not apply to seeds of
+// type t. A constructor changes the layout to its arguments, so seeds of its
+// type cannot be added.
+func (c *config) checkSeedType(t reflect.Type) error {
+	if c.constructor != nil {
+		return fmt.Errorf("values of %v are built by the constructor %s, add its arguments with Constructor.Add instead", t, c.constructor.name)
+	}
+	return c.gens.check(t)
+}
+
+// checkSequenceOptions returns an error if the options have anything but
+// generators and AllowNonCanonicalInputs, which are all that stateful tests
+// support.
+func (c *config) checkSequenceOptions() error {
+	unsupported := []string{}
+	if len(c.preconditions) > 0 {
+		unsupported = append(unsupported, "WithPrecondition")
+	}
+	if c.boundarySeeds {
+		unsupported = append(unsupported, "WithBoundarySeeds")
+	}
+	if c.constructor != nil {
+		unsupported = append(unsupported, "WithConstructor")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("stateful tests do not support %s, only generators and AllowNonCanonicalInputs", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// layoutKind returns optionsLayout if the options change the layout of the
+// input from LayoutOf the fuzzed type, and plainLayout otherwise.
+func (c *config) layoutKind() layoutKind {
+	if len(c.gens) > 0 || c.constructor != nil {
+		return optionsLayout
+	}
+	return plainLayout
+}
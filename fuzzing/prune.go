@@ -0,0 +1,111 @@
+package fuzzing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// PruneReport lists what PruneCorpus kept and removed.
+type PruneReport struct {
+	Kept    []string
+	Removed []PrunedEntry
+}
+
+// PrunedEntry is a corpus file removed by PruneCorpus.
+type PrunedEntry struct {
+	Path string
+	// Reason is why the file was removed.
+	Reason string
+}
+
+// PruneCorpus decodes every `go test fuzz v1` file at paths, files or
+// directories such as testdata/fuzz/FuzzMyFunc, with the current layout of T.
+// Files that do not decode, and files that decode to the same value as an
+// earlier file, are removed. Values are compared by their canonical flattened
+// fields, so entries that only differ behind nil pointers are duplicates.
+// Earlier paths take precedence, so list checked in corpora before the Go
+// fuzz cache. If dryRun is true, nothing is removed.
+//
+// Pass the options passed to Fuzz, as generators and constructors change the
+// layout. To not wipe a corpus decoded with the wrong layout, nothing is
+// removed if the layout recorded next to a corpus directory differs, or if
+// none of the files at a path decode.
+func PruneCorpus[T any](paths []string, dryRun bool, opts ...Option) (PruneReport, error) {
+	cfg := newConfig(opts)
+	t, err := cfg.decodeType(reflect.TypeFor[T]())
+	if err != nil {
+		return PruneReport{}, err
+	}
+	layout := layoutOf(t, cfg.gens)
+	corpora := make([][]corpusSlots, 0, len(paths))
+	for _, path := range paths {
+		if err := checkRecordedLayout(path, layout); err != nil {
+			return PruneReport{}, err
+		}
+		entries, err := readCorpus(path, t, cfg.gens)
+		if err != nil {
+			return PruneReport{}, err
+		}
+		decoded := 0
+		for _, entry := range entries {
+			if entry.err == nil {
+				decoded++
+			}
+		}
+		if len(entries) > 0 && decoded == 0 {
+			return PruneReport{}, fmt.Errorf("none of the %d entries at %s decode with the layout of %v, nothing was removed (first error: %v)",
+				len(entries), path, t, entries[0].err)
+		}
+		corpora = append(corpora, entries)
+	}
+
+	report := PruneReport{}
+	seen := map[string]string{}
+	for _, entries := range corpora {
+		for _, entry := range entries {
+			reason := ""
+			if entry.err != nil {
+				reason = entry.err.Error()
+			} else {
+				key := fmt.Sprintf("%#v", canonicalSlots(t, cfg.gens, entry.vals))
+				if duplicateOf, ok := seen[key]; ok {
+					reason = "duplicate of " + duplicateOf
+				} else {
+					seen[key] = entry.path
+				}
+			}
+			if reason == "" {
+				report.Kept = append(report.Kept, entry.path)
+				continue
+			}
+			if !dryRun {
+				if err := os.Remove(entry.path); err != nil {
+					return report, err
+				}
+			}
+			report.Removed = append(report.Removed, PrunedEntry{Path: entry.path, Reason: reason})
+		}
+	}
+	return report, nil
+}
+
+// checkRecordedLayout returns an error if the layout recorded next to the
+// corpus at path, a file or a directory, differs from layout.
+func checkRecordedLayout(path string, layout Layout) error {
+	corpusDir := path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		corpusDir = filepath.Dir(path)
+	}
+	sidecarPath := LayoutSidecarPath(corpusDir)
+	recorded, ok, err := readLayoutSidecar(sidecarPath)
+	if err != nil || !ok {
+		return err
+	}
+	if recorded.Fingerprint != layout.Fingerprint() {
+		return fmt.Errorf("the corpus in %s was written with the layout recorded in %s, not the layout of %s, nothing was removed: migrate the corpus first, or pass the options passed to Fuzz",
+			corpusDir, sidecarPath, layout.Type)
+	}
+	return nil
+}
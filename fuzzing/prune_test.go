@@ -0,0 +1,107 @@
+package fuzzing
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type pruneFoo struct {
+	S string
+	P *int
+}
+
+func TestPruneCorpus(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := t.TempDir()
+	a := writeCorpusFile(t, dir, "a", "go test fuzz v1\nstring(\"foo\")\nbool(false)\nint(0)\n")
+	// Only differs from a behind the nil pointer, so it decodes to the same value.
+	b := writeCorpusFile(t, dir, "b", "go test fuzz v1\nstring(\"foo\")\nbool(false)\nint(42)\n")
+	c := writeCorpusFile(t, dir, "c", "go test fuzz v1\nstring(\"foo\")\nbool(true)\nint(42)\n")
+	d := writeCorpusFile(t, dir, "d", "go test fuzz v1\nstring(\"foo\")\n")
+	// The cache duplicates the checked in corpus.
+	e := writeCorpusFile(t, cacheDir, "e", "go test fuzz v1\nstring(\"foo\")\nbool(true)\nint(42)\n")
+	f := writeCorpusFile(t, cacheDir, "f", "go test fuzz v1\nstring(\"bar\")\nbool(true)\nint(42)\n")
+
+	report, err := PruneCorpus[pruneFoo]([]string{dir, cacheDir}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{a, c, f}, report.Kept)
+	require.Len(t, report.Removed, 3)
+	assert.Equal(t, PrunedEntry{Path: b, Reason: "duplicate of " + a}, report.Removed[0])
+	assert.Equal(t, d, report.Removed[1].Path)
+	assert.Contains(t, report.Removed[1].Reason, "has 3 fields, got 1 values")
+	assert.Equal(t, PrunedEntry{Path: e, Reason: "duplicate of " + c}, report.Removed[2])
+
+	for _, path := range []string{a, c, f} {
+		assert.FileExists(t, path)
+	}
+	for _, path := range []string{b, d, e} {
+		assert.NoFileExists(t, path)
+	}
+}
+
+func TestPruneCorpus_DryRun(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpusFile(t, dir, "a", "go test fuzz v1\nstring(\"foo\")\nbool(false)\nint(0)\n")
+	b := writeCorpusFile(t, dir, "b", "go test fuzz v1\nstring(\"foo\")\nbool(false)\nint(42)\n")
+
+	report, err := PruneCorpus[pruneFoo]([]string{dir}, true)
+	require.NoError(t, err)
+	assert.Len(t, report.Removed, 1)
+	assert.FileExists(t, b)
+}
+
+func TestPruneCorpus_Missing(t *testing.T) {
+	_, err := PruneCorpus[pruneFoo]([]string{filepath.Join(t.TempDir(), "missing")}, false)
+	assert.Error(t, err)
+}
+
+type pruneGenFoo struct {
+	R io.Reader
+	P *int
+}
+
+func TestPruneCorpus_WithGen(t *testing.T) {
+	dir := t.TempDir()
+	a := writeCorpusFile(t, dir, "a", "go test fuzz v1\n[]byte(\"abc\")\nbool(false)\nint(0)\n")
+	b := writeCorpusFile(t, dir, "b", "go test fuzz v1\n[]byte(\"abc\")\nbool(false)\nint(42)\n")
+	c := writeCorpusFile(t, dir, "c", "go test fuzz v1\n[]byte(\"xyz\")\nbool(false)\nint(0)\n")
+
+	report, err := PruneCorpus[pruneGenFoo]([]string{dir}, false, WithGen(ReaderGen(nil)))
+	require.NoError(t, err)
+	assert.Equal(t, []string{a, c}, report.Kept)
+	assert.Equal(t, []PrunedEntry{{Path: b, Reason: "duplicate of " + a}}, report.Removed)
+}
+
+func TestPruneCorpus_NothingDecodes(t *testing.T) {
+	dir := t.TempDir()
+	a := writeCorpusFile(t, dir, "a", "go test fuzz v1\n[]byte(\"abc\")\nbool(false)\nint(0)\n")
+	b := writeCorpusFile(t, dir, "b", "go test fuzz v1\n[]byte(\"xyz\")\nbool(false)\nint(0)\n")
+
+	// Without the generator passed to Fuzz, no entry matches the layout.
+	_, err := PruneCorpus[pruneGenFoo]([]string{dir}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "none of the 2 entries at "+dir+" decode")
+	assert.FileExists(t, a)
+	assert.FileExists(t, b)
+}
+
+func TestPruneCorpus_RecordedLayoutMismatch(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "FuzzFoo")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	a := writeCorpusFile(t, dir, "a", "go test fuzz v1\nstring(\"foo\")\nbool(false)\nint(0)\n")
+	b := writeCorpusFile(t, dir, "b", "go test fuzz v1\nstring(\"foo\")\n")
+	require.NoError(t, writeLayoutSidecar(LayoutSidecarPath(dir), LayoutOf[pruneGenFoo]()))
+
+	_, err := PruneCorpus[pruneFoo]([]string{dir}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nothing was removed")
+	assert.FileExists(t, a)
+	assert.FileExists(t, b)
+
+	_, err = PruneCorpus[pruneFoo]([]string{a}, false)
+	assert.Error(t, err)
+}
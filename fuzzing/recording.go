@@ -0,0 +1,52 @@
+package fuzzing
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+// AddRecording adds the values recorded by the recording package in the JSON
+// lines file at path to the corpus. Fields that are no longer part of T are
+// ignored and new fields are zero, so old recordings stay usable. Values that
+// flatten to the same fields are only added once. Pass the options passed to
+// Fuzz, as generators change the layout. The number of added values is
+// returned.
+func AddRecording[T any](f TestingF, path string, opts ...Option) (int, error) {
+	cfg := newConfig(opts)
+	if err := cfg.checkSeedType(reflect.TypeFor[T]()); err != nil {
+		return 0, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	seen := map[string]bool{}
+	added := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var t T
+		if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+			return added, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		fields, err := flatten(t, cfg)
+		if err != nil {
+			return added, err
+		}
+		key := fmt.Sprintf("%#v", fields)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		addFields(f, fmt.Sprintf("%s:%d", path, line), fields)
+		added++
+	}
+	return added, scanner.Err()
+}
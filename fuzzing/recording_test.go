@@ -0,0 +1,87 @@
+package fuzzing
+
+import (
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/hugoklepsch/go-fuzz-all/recording"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type recordedFoo struct {
+	S string
+	P *int
+}
+
+func TestAddRecording(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	path := filepath.Join(t.TempDir(), "foo.jsonl")
+	r, err := recording.New[recordedFoo](path)
+	require.NoError(t, err)
+	require.NoError(t, r.Record(recordedFoo{S: "foo", P: ptr(42)}))
+	require.NoError(t, r.Record(recordedFoo{S: "bar"}))
+	require.NoError(t, r.Record(recordedFoo{S: "foo", P: ptr(42)}))
+	require.NoError(t, r.Close())
+
+	gomock.InOrder(
+		mockF.EXPECT().Add("foo", true, 42),
+		mockF.EXPECT().Add("bar", false, 0),
+	)
+	added, err := AddRecording[recordedFoo](mockF, path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+}
+
+func TestAddRecording_LayoutChanged(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	path := filepath.Join(t.TempDir(), "foo.jsonl")
+	// Recorded by an older version of recordedFoo, with a field that was removed since.
+	require.NoError(t, os.WriteFile(path, []byte(`{"S": "foo", "Removed": 1}`+"\n"), 0o644))
+
+	mockF.EXPECT().Add("foo", false, 0)
+	added, err := AddRecording[recordedFoo](mockF, path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+}
+
+func TestAddRecording_WithGen(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	path := filepath.Join(t.TempDir(), "foo.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"S": "foo", "P": 1}`+"\n"+`{"S": "bar", "P": 1}`+"\n"), 0o644))
+
+	mockF.EXPECT().Add([]byte{}, true, 1)
+	added, err := AddRecording[recordedFoo](mockF, path, WithFieldGen("S", Const("x")))
+	require.NoError(t, err)
+	// Both values flatten to the same fields, as S is generated.
+	assert.Equal(t, 1, added)
+
+	_, err = AddRecording[recordedFoo](mockF, path, WithFieldGen("T", Const("x")))
+	assert.EqualError(t, err, `no field of type string at field path "T" in fuzzing.recordedFoo to bind a generator to`)
+	takeSeedNames(mockF)
+}
+
+func TestAddRecording_Invalid(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	path := filepath.Join(t.TempDir(), "foo.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"S": "foo"}`+"\n"+`{"S": 1}`+"\n"), 0o644))
+
+	mockF.EXPECT().Add("foo", false, 0)
+	added, err := AddRecording[recordedFoo](mockF, path)
+	assert.ErrorContains(t, err, path+":2:")
+	assert.Equal(t, 1, added)
+}
@@ -0,0 +1,63 @@
+package fuzzing
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+const (
+	// rejectionWarnMinInputs is the number of inputs that must have been seen
+	// before the rejection rate is considered meaningful.
+	rejectionWarnMinInputs = 1000
+	// rejectionWarnRate is the fraction of rejected inputs above which a
+	// warning is printed.
+	rejectionWarnRate = 0.9
+)
+
+// rejectionWarnOutput is where the high rejection rate warning is written.
+var rejectionWarnOutput io.Writer = os.Stderr
+
+// Assume discards the current input if cond is false. Use it inside a fuzz
+// target to skip inputs that are outside the contract of the code under test.
+func Assume(t TestingT, cond bool) {
+	if !cond {
+		t.Helper()
+		t.SkipNow()
+	}
+}
+
+// rejectionStats counts the inputs seen by a fuzz target and how many of them
+// were rejected. It is safe for concurrent use.
+type rejectionStats struct {
+	total    atomic.Int64
+	rejected atomic.Int64
+	warned   atomic.Bool
+}
+
+func (s *rejectionStats) record(rejected bool) {
+	total := s.total.Add(1)
+	if !rejected {
+		return
+	}
+	s.rejected.Add(1)
+	s.maybeWarn(total)
+}
+
+func (s *rejectionStats) maybeWarn(total int64) {
+	if total < rejectionWarnMinInputs {
+		return
+	}
+	rejected := s.rejected.Load()
+	rate := float64(rejected) / float64(total)
+	if rate < rejectionWarnRate {
+		return
+	}
+	if s.warned.Swap(true) {
+		return
+	}
+	fmt.Fprintf(rejectionWarnOutput,
+		"go-fuzz-all: %d of %d inputs (%.1f%%) were rejected; consider tightening preconditions or generators\n",
+		rejected, total, rate*100)
+}
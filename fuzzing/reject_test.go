@@ -0,0 +1,152 @@
+package fuzzing
+
+import (
+	"bytes"
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestFuzz_WithPrecondition_SkipsRejectedInputs(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	type Foo struct {
+		I int
+	}
+	var target func(*testing.T, int)
+	mockF.EXPECT().Fuzz(gomock.Any()).Do(func(fuzzTarget any) {
+		target = fuzzTarget.(func(*testing.T, int))
+	})
+	var called []int
+	Fuzz(mockF, func(t *testing.T, foo Foo) {
+		called = append(called, foo.I)
+	}, WithPrecondition(func(foo Foo) bool { return foo.I > 0 }))
+
+	skipped := false
+	t.Run("rejected", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		target(t, -1)
+	})
+	assert.True(t, skipped)
+	t.Run("accepted", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		target(t, 1)
+	})
+	assert.False(t, skipped)
+	assert.Equal(t, []int{1}, called)
+}
+
+func TestFuzz_WithPrecondition_WarnsWhenRateIsHigh(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+	out := &bytes.Buffer{}
+	oldOutput := rejectionWarnOutput
+	rejectionWarnOutput = out
+	defer func() { rejectionWarnOutput = oldOutput }()
+
+	var target func(*testing.T, int)
+	mockF.EXPECT().Fuzz(gomock.Any()).Do(func(fuzzTarget any) {
+		target = fuzzTarget.(func(*testing.T, int))
+	})
+	Fuzz(mockF, func(t *testing.T, i int) {}, WithPrecondition(func(i int) bool { return i%10 == 0 }))
+
+	// 1 in 10 inputs is accepted, right at the threshold.
+	for i := 0; i < rejectionWarnMinInputs; i++ {
+		t.Run("input", func(t *testing.T) {
+			target(t, i)
+		})
+	}
+	assert.Equal(t, "go-fuzz-all: 900 of 1000 inputs (90.0%) were rejected; consider tightening preconditions or generators\n", out.String())
+}
+
+func TestFuzz_Assume_SkipsRejectedInputs(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	var target func(*testing.T, string)
+	mockF.EXPECT().Fuzz(gomock.Any()).Do(func(fuzzTarget any) {
+		target = fuzzTarget.(func(*testing.T, string))
+	})
+	Fuzz(mockF, func(t *testing.T, s string) {
+		Assume(t, s != "")
+	})
+
+	skipped := false
+	t.Run("rejected", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		target(t, "")
+	})
+	assert.True(t, skipped)
+}
+
+func TestRejectionStats_WarnsOnceWhenRateIsHigh(t *testing.T) {
+	out := &bytes.Buffer{}
+	oldOutput := rejectionWarnOutput
+	rejectionWarnOutput = out
+	defer func() { rejectionWarnOutput = oldOutput }()
+
+	stats := rejectionStats{}
+	for i := 0; i < rejectionWarnMinInputs-1; i++ {
+		stats.record(true)
+	}
+	assert.Empty(t, out.String(), "must not warn before enough inputs were seen")
+
+	stats.record(true)
+	stats.record(true)
+	assert.Contains(t, out.String(), "1000 of 1000 inputs (100.0%) were rejected")
+	assert.Equal(t, 1, bytes.Count(out.Bytes(), []byte("go-fuzz-all:")))
+}
+
+func TestRejectionStats_Threshold(t *testing.T) {
+	for name, tc := range map[string]struct {
+		accepted, rejected int
+		warns              bool
+	}{
+		"at the rate":       {accepted: 100, rejected: 900, warns: true},
+		"below the rate":    {accepted: 101, rejected: 899, warns: false},
+		"at the minimum":    {accepted: 0, rejected: rejectionWarnMinInputs, warns: true},
+		"below the minimum": {accepted: 0, rejected: rejectionWarnMinInputs - 1, warns: false},
+		"above the minimum": {accepted: 200, rejected: 900, warns: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			oldOutput := rejectionWarnOutput
+			rejectionWarnOutput = out
+			defer func() { rejectionWarnOutput = oldOutput }()
+
+			stats := rejectionStats{}
+			for i := 0; i < tc.accepted; i++ {
+				stats.record(false)
+			}
+			for i := 0; i < tc.rejected; i++ {
+				stats.record(true)
+			}
+			assert.Equal(t, tc.warns, out.Len() > 0, out.String())
+			assert.Equal(t, int64(tc.accepted+tc.rejected), stats.total.Load())
+			assert.Equal(t, int64(tc.rejected), stats.rejected.Load())
+		})
+	}
+}
+
+func TestRejectionStats_NoWarningWhenRateIsLow(t *testing.T) {
+	out := &bytes.Buffer{}
+	oldOutput := rejectionWarnOutput
+	rejectionWarnOutput = out
+	defer func() { rejectionWarnOutput = oldOutput }()
+
+	stats := rejectionStats{}
+	for i := 0; i < 2*rejectionWarnMinInputs; i++ {
+		stats.record(i%2 == 0)
+	}
+	assert.Empty(t, out.String())
+	assert.Equal(t, int64(2*rejectionWarnMinInputs), stats.total.Load())
+	assert.Equal(t, int64(rejectionWarnMinInputs), stats.rejected.Load())
+}
@@ -0,0 +1,181 @@
+package fuzzing
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strings"
+)
+
+// failureReporter is the subset of *testing.T used to report a failing input.
+type failureReporter interface {
+	Helper()
+	Failed() bool
+	Errorf(format string, args ...any)
+	Logf(format string, args ...any)
+}
+
+// reportFailure attributes a failure, or the panic recovered from the fuzz
+// target, to the decoded input, and to the name of the seed if it has one.
+// Nothing is reported for passing inputs.
+func reportFailure(t failureReporter, recovered any, seedName string, input any) {
+	t.Helper()
+	if recovered != nil {
+		t.Errorf("fuzz target panicked: %v\n%s\n%s", recovered, describeFailingInput(seedName, input), debug.Stack())
+		return
+	}
+	if t.Failed() {
+		t.Logf("%s", describeFailingInput(seedName, input))
+	}
+}
+
+// generatedInput is a failing input with fields built by generators. input
+// is the decoded input, or the constructorCall that built it, with those
+// fields zero.
+type generatedInput struct {
+	input  any
+	fields []generatedField
+}
+
+// describeFailingInput describes the decoded input both by field path and as
+// a Go literal that can be pasted into a regression test. Inputs built by a
+// constructor are described as a call to the constructor instead. Fields built
+// by generators are described by the input of their generators, and by the
+// calls made to them if they are fakes.
+func describeFailingInput(seedName string, input any) string {
+	var generated []generatedField
+	if g, ok := input.(generatedInput); ok {
+		input = g.input
+		generated = g.fields
+	}
+	var description, literal string
+	if call, ok := input.(constructorCall); ok {
+		description, literal = call.describe()
+		description += "\nas Go call:\n\t" + literal
+	} else {
+		literal = GoLiteral(input)
+		description = describeInput(input) + "\nas Go literal:\n\t" + literal
+	}
+	if seedName != "" {
+		description = fmt.Sprintf("seed %q\n%s", seedName, description)
+	}
+	if UsesPtrHelper(literal) {
+		description += "\n\t// " + PtrHelper
+	}
+	if len(generated) > 0 {
+		description += "\n" + describeGenerated(generated)
+	}
+	return description
+}
+
+// describeGenerated describes fields built by generators by the input of
+// their generators, and by the calls made to fakes with their results.
+func describeGenerated(fields []generatedField) string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "generated fields, zero above, by the input of their generators:")
+	for _, field := range fields {
+		fmt.Fprintf(sb, "\n\t%s = []byte(%q)", displayFieldPath(field.path), field.data)
+		if !field.value.IsValid() || !field.value.CanInterface() {
+			continue
+		}
+		fake, ok := field.value.Interface().(interface{ Calls() []MethodCall })
+		if !ok || (field.value.Kind() == reflect.Interface && field.value.IsNil()) {
+			continue
+		}
+		for _, call := range fake.Calls() {
+			fmt.Fprintf(sb, "\n\t\t%s", describeFakeCall(call))
+		}
+	}
+	return sb.String()
+}
+
+// describeFakeCall renders a call recorded by a Fake, and its results.
+func describeFakeCall(call MethodCall) string {
+	args := make([]string, 0, len(call.Args))
+	for _, arg := range call.Args {
+		args = append(args, GoLiteral(arg))
+	}
+	results := make([]string, 0, len(call.Results))
+	for _, result := range call.Results {
+		results = append(results, renderResult(result))
+	}
+	rendered := fmt.Sprintf("%s(%s)", call.Method, strings.Join(args, ", "))
+	switch len(results) {
+	case 0:
+		return rendered
+	case 1:
+		return rendered + " returned " + results[0]
+	default:
+		return rendered + " returned (" + strings.Join(results, ", ") + ")"
+	}
+}
+
+// describeInput pretty-prints a decoded input, one field path per line.
+func describeInput(input any) string {
+	value := reflect.ValueOf(input)
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "decoded input of type %v:", value.Type())
+	describer := fieldPathDescriber{sb: sb}
+	describer.describeValue("", value)
+	return sb.String()
+}
+
+type fieldPathDescriber struct {
+	sb *strings.Builder
+}
+
+func (d *fieldPathDescriber) line(path string, format string, args ...any) {
+	if path == "" {
+		path = "<root>"
+	}
+	fmt.Fprintf(d.sb, "\n\t%s = %s", path, fmt.Sprintf(format, args...))
+}
+
+func (d *fieldPathDescriber) describeValue(path string, value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			d.line(path, "nil")
+			return
+		}
+		if value.Elem().Kind() == reflect.Struct {
+			// Like Go, dereference pointers to structs implicitly.
+			d.describeValue(path, value.Elem())
+			return
+		}
+		d.line(path, "&%s", formatLeaf(value.Elem()))
+	case reflect.Struct:
+		t := value.Type()
+		exported := 0
+		for i := 0; i < t.NumField(); i++ {
+			structField := t.Field(i)
+			// Unexported fields are never decoded, so they are not interesting.
+			if !structField.IsExported() {
+				continue
+			}
+			exported++
+			fieldPath := structField.Name
+			if path != "" {
+				fieldPath = path + "." + structField.Name
+			}
+			d.describeValue(fieldPath, value.Field(i))
+		}
+		if exported == 0 {
+			d.line(path, "%v{}", t)
+		}
+	default:
+		d.line(path, "%s", formatLeaf(value))
+	}
+}
+
+func formatLeaf(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Slice, reflect.UnsafePointer:
+		if value.IsNil() {
+			return "nil"
+		}
+	case reflect.String:
+		return fmt.Sprintf("%q", value.String())
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,84 @@
+package fuzzing
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type fakeReporter struct {
+	failed bool
+	errors []string
+	logs   []string
+}
+
+func (r *fakeReporter) Helper() {}
+
+func (r *fakeReporter) Failed() bool {
+	return r.failed
+}
+
+func (r *fakeReporter) Errorf(format string, args ...any) {
+	r.failed = true
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *fakeReporter) Logf(format string, args ...any) {
+	r.logs = append(r.logs, fmt.Sprintf(format, args...))
+}
+
+type reportNested struct {
+	F *float32
+	C complex64
+}
+
+type reportFoo struct {
+	S string
+	P *int
+	N *reportNested
+	M *reportNested
+	i int
+}
+
+func TestDescribeInput_FieldPaths(t *testing.T) {
+	input := reportFoo{
+		S: "foo\xff",
+		P: ptr(42),
+		N: &reportNested{C: complex64(12)},
+	}
+	expected := "decoded input of type fuzzing.reportFoo:" +
+		"\n\tS = \"foo\\xff\"" +
+		"\n\tP = &42" +
+		"\n\tN.F = nil" +
+		"\n\tN.C = (12+0i)" +
+		"\n\tM = nil"
+	assert.Equal(t, expected, describeInput(input))
+}
+
+func TestDescribeInput_Root(t *testing.T) {
+	assert.Equal(t, "decoded input of type int:\n\t<root> = 42", describeInput(42))
+}
+
+func TestReportFailure_Passing(t *testing.T) {
+	r := &fakeReporter{}
+	reportFailure(r, nil, "", reportFoo{})
+	assert.Empty(t, r.errors)
+	assert.Empty(t, r.logs)
+}
+
+func TestReportFailure_Failed(t *testing.T) {
+	r := &fakeReporter{failed: true}
+	reportFailure(r, nil, "", reportFoo{S: "foo"})
+	assert.Empty(t, r.errors)
+	assert.Len(t, r.logs, 1)
+	assert.Contains(t, r.logs[0], "S = \"foo\"")
+}
+
+func TestReportFailure_Panicked(t *testing.T) {
+	r := &fakeReporter{}
+	reportFailure(r, "uh oh", "", reportFoo{S: "foo"})
+	assert.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "fuzz target panicked: uh oh")
+	assert.Contains(t, r.errors[0], "S = \"foo\"")
+	assert.Empty(t, r.logs)
+}
@@ -0,0 +1,76 @@
+package fuzzing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SeedDir returns the directory, relative to the package directory, that Fuzz
+// reads the typed seeds of the fuzz test fuzzName from. Unlike a corpus
+// directory, the seeds in it are keyed by field name, so they survive changes
+// to the layout of the fuzzed type.
+func SeedDir(fuzzName string) string {
+	return filepath.Join("testdata", "fuzz-all", fuzzName)
+}
+
+// addJSONSeeds adds every *.json file in dir to the corpus. Each file holds
+// the JSON encoding of a single value of type t. A missing dir is not an
+// error.
+func addJSONSeeds(f TestingF, dir string, t reflect.Type, gens genBindings) error {
+	paths, err := seedFiles(dir, ".json")
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		value, err := readJSONSeed(path, t)
+		if err != nil {
+			return err
+		}
+		fieldsTraverser := anyToFieldsTraverser{gens: gens}
+		fieldsTraverser.traverseValue(value)
+		addFields(f, path, fieldsTraverser.fields)
+	}
+	return nil
+}
+
+func readJSONSeed(path string, t reflect.Type) (reflect.Value, error) {
+	value := reflect.New(t)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return value.Elem(), err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Catch typos in field names, rather than silently ignoring the field.
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value.Interface()); err != nil {
+		return value.Elem(), fmt.Errorf("%s: %w", path, err)
+	}
+	return value.Elem(), nil
+}
+
+// seedFiles returns the files in dir with the extension ext, in a stable
+// order.
+func seedFiles(dir string, ext string) ([]string, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	paths := []string{}
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ext) {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, dirEntry.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,61 @@
+package fuzzing
+
+import (
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type seedFoo struct {
+	S string
+	P *int
+	B bool
+}
+
+func TestSeedDir(t *testing.T) {
+	assert.Equal(t, filepath.Join("testdata", "fuzz-all", "FuzzFoo"), SeedDir("FuzzFoo"))
+}
+
+func TestAddJSONSeeds(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"S": "foo", "P": 42}`), 0o644))
+	// Field order does not matter, and missing fields are zero.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"B": true, "S": "bar"}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte(`not a seed`), 0o644))
+
+	gomock.InOrder(
+		mockF.EXPECT().Add("bar", false, 0, true),
+		mockF.EXPECT().Add("foo", true, 42, false),
+	)
+	require.NoError(t, addJSONSeeds(mockF, dir, reflect.TypeFor[seedFoo](), nil))
+}
+
+func TestAddJSONSeeds_MissingDir(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	require.NoError(t, addJSONSeeds(mockF, filepath.Join(t.TempDir(), "missing"), reflect.TypeFor[seedFoo](), nil))
+}
+
+func TestAddJSONSeeds_UnknownField(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"Typo": "foo"}`), 0o644))
+	err := addJSONSeeds(mockF, dir, reflect.TypeFor[seedFoo](), nil)
+	assert.ErrorContains(t, err, path)
+	assert.ErrorContains(t, err, `unknown field "Typo"`)
+}
@@ -0,0 +1,167 @@
+package fuzzing
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// defaultMaxSteps is the maximum length of a decoded sequence, unless
+// configured otherwise.
+const defaultMaxSteps = 32
+
+// sequenceCodec encodes sequences of steps, each a value of one of a fixed set
+// of step types, into the []byte passed to the fuzz target of a stateful test.
+//
+// Like the elements of SliceOf, every step is preceded by a bool that tells
+// whether there is one more step, so that the fuzzing engine can grow and
+// shrink the sequence. The bool is followed by the index of the type of the
+// step, and then by the flattened fields of the step. Steps of pointer types
+// are decoded as what they point at, so they are never nil.
+type sequenceCodec struct {
+	types     []reflect.Type
+	slotTypes [][]reflect.Type
+	maxSteps  int
+	gens      genBindings
+}
+
+func newSequenceCodec(types []reflect.Type, maxSteps int, gens genBindings) (*sequenceCodec, error) {
+	if len(types) == 0 {
+		return nil, fmt.Errorf("no steps to choose from")
+	}
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxSteps
+	}
+	c := &sequenceCodec{types: types, maxSteps: maxSteps, gens: gens}
+	seen := map[reflect.Type]bool{}
+	for _, t := range types {
+		if seen[t] {
+			return nil, fmt.Errorf("step type %v is listed twice", t)
+		}
+		seen[t] = true
+		fieldsTraverser := anyToFieldsTraverser{gens: gens}
+		fieldsTraverser.traverseType(decodedStepType(t))
+		c.slotTypes = append(c.slotTypes, fieldsTraverser.fieldsTypes)
+	}
+	for _, binding := range gens {
+		var err error
+		for _, t := range types {
+			if err = (genBindings{binding}).check(decodedStepType(t)); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// layout returns the layout of the steps of the sequence. The fields of the
+// step type at index i are at paths prefixed with "[i]", after a field at
+// "[i]" that stands for the step type itself, so that adding, removing or
+// reordering step types changes the layout even if they have no fields. The
+// maximum number of steps does not change how steps are decoded, so it is not
+// part of the layout.
+func (c *sequenceCodec) layout() Layout {
+	names := make([]string, 0, len(c.types))
+	fields := []LayoutField{}
+	for i, t := range c.types {
+		names = append(names, t.String())
+		step := fmt.Sprintf("[%d]", i)
+		fields = append(fields, LayoutField{Path: step, Type: "step"})
+		fieldsTraverser := anyToFieldsTraverser{gens: c.gens}
+		fieldsTraverser.traverseType(decodedStepType(t))
+		for j, fieldType := range fieldsTraverser.fieldsTypes {
+			fields = append(fields, LayoutField{
+				Path: joinFieldPath(step, displayFieldPath(fieldsTraverser.fieldsPaths[j])),
+				Type: fieldType.String(),
+			})
+		}
+	}
+	return Layout{Type: "sequence of " + strings.Join(names, ", "), Fields: fields}
+}
+
+// decodedStepType returns the type that is decoded for steps of type t.
+func decodedStepType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
+
+// decode decodes a sequence from data. The builder of every step is returned
+// to tell whether the input was canonical and accepted by the generators.
+func (c *sequenceCodec) decode(data []byte) ([]reflect.Value, []*buildAnyTraverser) {
+	source := NewSource(data)
+	steps := []reflect.Value{}
+	builders := []*buildAnyTraverser{}
+	for len(steps) < c.maxSteps && source.Bool() {
+		i := source.IntN(len(c.types))
+		step, builder := decodeSourceValue(decodedStepType(c.types[i]), c.slotTypes[i], source, c.gens)
+		if c.types[i].Kind() == reflect.Pointer {
+			stepPtr := reflect.New(step.Type())
+			stepPtr.Elem().Set(step)
+			step = stepPtr
+		}
+		steps = append(steps, step)
+		builders = append(builders, builder)
+	}
+	return steps, builders
+}
+
+// encode encodes steps such that decode decodes them back, or returns an
+// error if a step is not of one of the step types. Fields bound to generators
+// are encoded as empty bytes.
+func (c *sequenceCodec) encode(steps []reflect.Value) ([]byte, error) {
+	if len(steps) > c.maxSteps {
+		return nil, fmt.Errorf("%d steps is more than the maximum of %d", len(steps), c.maxSteps)
+	}
+	data := []byte{}
+	for _, step := range steps {
+		i := c.typeIndex(step.Type())
+		if i < 0 {
+			return nil, fmt.Errorf("%v is not one of the step types", step.Type())
+		}
+		if step.Kind() == reflect.Pointer {
+			if step.IsNil() {
+				return nil, fmt.Errorf("step of type %v is nil", step.Type())
+			}
+			step = step.Elem()
+		}
+		data = appendValue(data, reflect.ValueOf(true))
+		data = appendUintN(data, uint64(i), uint64(len(c.types)))
+		fieldsTraverser := anyToFieldsTraverser{gens: c.gens}
+		fieldsTraverser.traverseValue(step)
+		for _, field := range fieldsTraverser.fields {
+			data = appendValue(data, reflect.ValueOf(field))
+		}
+	}
+	return data, nil
+}
+
+func (c *sequenceCodec) typeIndex(t reflect.Type) int {
+	for i, stepType := range c.types {
+		if stepType == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// shrinkSequence returns a subsequence of steps for which fails still returns
+// true, removing one step at a time for as long as that keeps it failing.
+// fails must return true for steps.
+func shrinkSequence[S any](steps []S, fails func([]S) bool) []S {
+	for removed := true; removed; {
+		removed = false
+		for i := len(steps) - 1; i >= 0; i-- {
+			candidate := append(append([]S{}, steps[:i]...), steps[i+1:]...)
+			if fails(candidate) {
+				steps = candidate
+				removed = true
+			}
+		}
+	}
+	return steps
+}
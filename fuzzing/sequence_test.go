@@ -0,0 +1,105 @@
+package fuzzing
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"testing"
+)
+
+type seqSet struct {
+	Key string
+	Val *int
+}
+
+type seqClear struct{}
+
+func TestSequenceCodec_RoundTrip(t *testing.T) {
+	codec, err := newSequenceCodec([]reflect.Type{reflect.TypeFor[seqSet](), reflect.TypeFor[*seqClear]()}, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, defaultMaxSteps, codec.maxSteps)
+
+	steps := []reflect.Value{
+		reflect.ValueOf(seqSet{Key: "a", Val: ptr(1)}),
+		reflect.ValueOf(&seqClear{}),
+		reflect.ValueOf(seqSet{Key: "b"}),
+	}
+	data, err := codec.encode(steps)
+	require.NoError(t, err)
+	decoded, builders := codec.decode(data)
+	require.Len(t, decoded, 3)
+	require.Len(t, builders, 3)
+	for i := range steps {
+		assert.Equal(t, steps[i].Interface(), decoded[i].Interface())
+	}
+
+	decoded, _ = codec.decode(nil)
+	assert.Empty(t, decoded)
+}
+
+func TestSequenceCodec_MaxSteps(t *testing.T) {
+	codec, err := newSequenceCodec([]reflect.Type{reflect.TypeFor[seqClear]()}, 2, nil)
+	require.NoError(t, err)
+	decoded, _ := codec.decode([]byte{1, 0, 1, 0, 1, 0})
+	assert.Len(t, decoded, 2)
+	_, err = codec.encode([]reflect.Value{reflect.ValueOf(seqClear{}), reflect.ValueOf(seqClear{}), reflect.ValueOf(seqClear{})})
+	assert.EqualError(t, err, "3 steps is more than the maximum of 2")
+}
+
+func TestSequenceCodec_Invalid(t *testing.T) {
+	_, err := newSequenceCodec(nil, 0, nil)
+	assert.EqualError(t, err, "no steps to choose from")
+	_, err = newSequenceCodec([]reflect.Type{reflect.TypeFor[seqClear](), reflect.TypeFor[seqClear]()}, 0, nil)
+	assert.EqualError(t, err, "step type fuzzing.seqClear is listed twice")
+	gens := newConfig([]Option{WithFieldGen("Name", Const("x"))}).gens
+	_, err = newSequenceCodec([]reflect.Type{reflect.TypeFor[seqSet]()}, 0, gens)
+	assert.EqualError(t, err, `no field of type string at field path "Name" in fuzzing.seqSet to bind a generator to`)
+
+	codec, err := newSequenceCodec([]reflect.Type{reflect.TypeFor[*seqClear]()}, 0, nil)
+	require.NoError(t, err)
+	_, err = codec.encode([]reflect.Value{reflect.ValueOf(seqSet{})})
+	assert.EqualError(t, err, "fuzzing.seqSet is not one of the step types")
+	_, err = codec.encode([]reflect.Value{reflect.ValueOf((*seqClear)(nil))})
+	assert.EqualError(t, err, "step of type *fuzzing.seqClear is nil")
+}
+
+func TestSequenceCodec_Layout(t *testing.T) {
+	codec, err := newSequenceCodec([]reflect.Type{reflect.TypeFor[seqSet](), reflect.TypeFor[*seqClear](), reflect.TypeFor[int]()}, 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, Layout{
+		Type: "sequence of fuzzing.seqSet, *fuzzing.seqClear, int",
+		Fields: []LayoutField{
+			{Path: "[0]", Type: "step"},
+			{Path: "[0].Key", Type: "string"},
+			{Path: "[0].Val", Type: "bool"},
+			{Path: "[0].*Val", Type: "int"},
+			{Path: "[1]", Type: "step"},
+			{Path: "[2]", Type: "step"},
+			{Path: "[2].<root>", Type: "int"},
+		},
+	}, codec.layout())
+
+	// Reordering step types changes the layout, even those without fields.
+	reordered, err := newSequenceCodec([]reflect.Type{reflect.TypeFor[seqSet](), reflect.TypeFor[int](), reflect.TypeFor[*seqClear]()}, 0, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, codec.layout().Fingerprint(), reordered.layout().Fingerprint())
+	// The maximum number of steps does not.
+	limited, err := newSequenceCodec(codec.types, 2, nil)
+	require.NoError(t, err)
+	assert.Equal(t, codec.layout().Fingerprint(), limited.layout().Fingerprint())
+}
+
+func TestShrinkSequence(t *testing.T) {
+	// Fails whenever 3 comes after 1.
+	fails := func(steps []int) bool {
+		seenOne := false
+		for _, step := range steps {
+			seenOne = seenOne || step == 1
+			if seenOne && step == 3 {
+				return true
+			}
+		}
+		return false
+	}
+	assert.Equal(t, []int{1, 3}, shrinkSequence([]int{4, 1, 2, 1, 5, 3, 3}, fails))
+}
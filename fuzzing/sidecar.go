@@ -0,0 +1,127 @@
+package fuzzing
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// layoutSidecar is the content of the file, next to a corpus directory, that
+// records the layout the corpus was written with.
+type layoutSidecar struct {
+	Fingerprint string `json:"fingerprint"`
+	Layout
+}
+
+// LayoutSidecarPath returns the path of the file that records the layout of
+// the corpus in corpusDir. The file is next to, not in, the corpus directory,
+// as the Go fuzzing engine reads every file in it as a corpus entry.
+func LayoutSidecarPath(corpusDir string) string {
+	return filepath.Clean(corpusDir) + ".layout.json"
+}
+
+// layoutKind tells how a layout was derived, which decides how a corpus
+// written with another layout can be migrated.
+type layoutKind int
+
+const (
+	// plainLayout is the LayoutOf the fuzzed type.
+	plainLayout layoutKind = iota
+	// optionsLayout depends on the generators and constructor passed to Fuzz.
+	optionsLayout
+	// sequenceLayout is the layout of the steps of a stateful test, whose
+	// corpus entries are a single []byte each.
+	sequenceLayout
+)
+
+// checkLayoutSidecar compares layout with the layout recorded next to
+// corpusDir, and records it if there is nothing to compare with yet. kind
+// decides the migration hint when they differ.
+func checkLayoutSidecar(corpusDir string, layout Layout, fuzzing bool, kind layoutKind) error {
+	sidecarPath := LayoutSidecarPath(corpusDir)
+	fingerprint := layout.Fingerprint()
+	recorded, ok, err := readLayoutSidecar(sidecarPath)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if !fuzzing && !hasCorpusFiles(corpusDir) {
+			// Nothing to protect, and the engine will not write to the corpus.
+			return nil
+		}
+		return writeLayoutSidecar(sidecarPath, layout)
+	}
+	if recorded.Fingerprint == fingerprint {
+		return nil
+	}
+	if !hasCorpusFiles(corpusDir) {
+		// No corpus was written with the old layout, so just record the new one.
+		return writeLayoutSidecar(sidecarPath, layout)
+	}
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "the layout of %s changed since the corpus in %s was written (fingerprint %s, recorded %s in %s):",
+		layout.Type, corpusDir, fingerprint, recorded.Fingerprint, sidecarPath)
+	for _, line := range diffLayouts(recorded.Layout, layout) {
+		fmt.Fprintf(sb, "\n\t%s", line)
+	}
+	if kind == sequenceLayout {
+		fmt.Fprintf(sb, "\nThe steps of a sequence are encoded in a single []byte, which fuzz-all migrate cannot rewrite. "+
+			"Remove the entries of %s, or add them again with the new layout, then remove %s to record it.", corpusDir, sidecarPath)
+		return errors.New(sb.String())
+	}
+	fmt.Fprintf(sb, "\nMigrate the corpus to the new layout, then remove %s to record it:", sidecarPath)
+	if kind == optionsLayout {
+		fmt.Fprintf(sb, "\n\tfuzz-all layout -type <type> -opts <options> > new.json")
+	} else {
+		fmt.Fprintf(sb, "\n\tfuzz-all layout -type <type> > new.json")
+	}
+	fmt.Fprintf(sb, "\n\tfuzz-all migrate -from %s -to new.json %s", sidecarPath, corpusDir)
+	if kind == optionsLayout {
+		fmt.Fprintf(sb, "\nwhere <options> is a []fuzzing.Option variable of the package with the generators and constructor passed to Fuzz, as they change the layout.")
+	}
+	return errors.New(sb.String())
+}
+
+// readLayoutSidecar reads the layout sidecar at path, and reports whether it
+// exists.
+func readLayoutSidecar(path string) (layoutSidecar, bool, error) {
+	recorded := layoutSidecar{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return recorded, false, nil
+	}
+	if err != nil {
+		return recorded, false, err
+	}
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return recorded, false, fmt.Errorf("%s: %w", path, err)
+	}
+	return recorded, true, nil
+}
+
+func writeLayoutSidecar(path string, layout Layout) error {
+	data, err := json.MarshalIndent(layoutSidecar{Fingerprint: layout.Fingerprint(), Layout: layout}, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func hasCorpusFiles(corpusDir string) bool {
+	paths, err := corpusFiles(corpusDir)
+	return err == nil && len(paths) > 0
+}
+
+// isFuzzing reports whether the test binary was started with -test.fuzz, in
+// which case the Go fuzzing engine may write new entries to the corpus.
+func isFuzzing() bool {
+	fuzzFlag := flag.Lookup("test.fuzz")
+	return fuzzFlag != nil && fuzzFlag.Value.String() != ""
+}
@@ -0,0 +1,103 @@
+package fuzzing
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type sidecarOld struct {
+	S string
+	I int
+}
+
+type sidecarNew struct {
+	I int
+	S string
+	P *bool
+}
+
+func TestLayoutSidecarPath(t *testing.T) {
+	assert.Equal(t, filepath.Join("testdata", "fuzz", "FuzzFoo.layout.json"), LayoutSidecarPath(CorpusDir("FuzzFoo")))
+	assert.Equal(t, "corpus.layout.json", LayoutSidecarPath("corpus/"))
+}
+
+func TestCheckLayoutSidecar_NothingToProtect(t *testing.T) {
+	corpusDir := filepath.Join(t.TempDir(), "FuzzFoo")
+	require.NoError(t, checkLayoutSidecar(corpusDir, LayoutOf[sidecarOld](), false, plainLayout))
+	assert.NoFileExists(t, LayoutSidecarPath(corpusDir))
+}
+
+func TestCheckLayoutSidecar_RecordedWhenFuzzing(t *testing.T) {
+	corpusDir := filepath.Join(t.TempDir(), "FuzzFoo")
+	require.NoError(t, checkLayoutSidecar(corpusDir, LayoutOf[sidecarOld](), true, plainLayout))
+	assert.FileExists(t, LayoutSidecarPath(corpusDir))
+	require.NoError(t, checkLayoutSidecar(corpusDir, LayoutOf[sidecarOld](), false, plainLayout))
+}
+
+func TestCheckLayoutSidecar_RecordedForExistingCorpus(t *testing.T) {
+	corpusDir := filepath.Join(t.TempDir(), "FuzzFoo")
+	_, err := WriteCorpusFile(corpusDir, sidecarOld{S: "foo"})
+	require.NoError(t, err)
+	require.NoError(t, checkLayoutSidecar(corpusDir, LayoutOf[sidecarOld](), false, plainLayout))
+	assert.FileExists(t, LayoutSidecarPath(corpusDir))
+}
+
+func TestCheckLayoutSidecar_Mismatch(t *testing.T) {
+	corpusDir := filepath.Join(t.TempDir(), "FuzzFoo")
+	_, err := WriteCorpusFile(corpusDir, sidecarOld{S: "foo"})
+	require.NoError(t, err)
+	require.NoError(t, checkLayoutSidecar(corpusDir, LayoutOf[sidecarOld](), false, plainLayout))
+
+	err = checkLayoutSidecar(corpusDir, LayoutOf[sidecarNew](), false, plainLayout)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the layout of fuzzing.sidecarNew changed since the corpus in "+corpusDir+" was written")
+	assert.Contains(t, err.Error(), "\n\tadded: P, *P\n\tmoved: I (1 -> 0), S (0 -> 1)")
+	assert.Contains(t, err.Error(), "fuzz-all migrate -from "+LayoutSidecarPath(corpusDir)+" -to new.json "+corpusDir)
+}
+
+func TestCheckLayoutSidecar_MismatchWithCustomLayout(t *testing.T) {
+	corpusDir := filepath.Join(t.TempDir(), "FuzzFoo")
+	_, err := WriteCorpusFile(corpusDir, sidecarOld{S: "foo"})
+	require.NoError(t, err)
+	require.NoError(t, checkLayoutSidecar(corpusDir, LayoutOf[sidecarOld](), false, optionsLayout))
+
+	err = checkLayoutSidecar(corpusDir, LayoutOf[sidecarNew](), false, optionsLayout)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fuzz-all layout -type <type> -opts <options> > new.json")
+	assert.Contains(t, err.Error(), "<options> is a []fuzzing.Option variable")
+}
+
+func TestCheckLayoutSidecar_MismatchOfSequence(t *testing.T) {
+	corpusDir := filepath.Join(t.TempDir(), "FuzzFoo")
+	_, err := WriteCorpusFile(corpusDir, []byte{1, 0})
+	require.NoError(t, err)
+	oldCodec, err := newSequenceCodec([]reflect.Type{reflect.TypeFor[sidecarOld]()}, 0, nil)
+	require.NoError(t, err)
+	require.NoError(t, checkLayoutSidecar(corpusDir, oldCodec.layout(), false, sequenceLayout))
+
+	newCodec, err := newSequenceCodec([]reflect.Type{reflect.TypeFor[sidecarNew]()}, 0, nil)
+	require.NoError(t, err)
+	err = checkLayoutSidecar(corpusDir, newCodec.layout(), false, sequenceLayout)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the layout of sequence of fuzzing.sidecarNew changed since the corpus in "+corpusDir+" was written")
+	assert.Contains(t, err.Error(), "\n\tadded: [0].P, [0].*P\n\tmoved: [0].I (2 -> 1), [0].S (1 -> 2)")
+	assert.Contains(t, err.Error(), "fuzz-all migrate cannot rewrite")
+	assert.NotContains(t, err.Error(), "new.json")
+}
+
+func TestCheckLayoutSidecar_MismatchWithoutCorpus(t *testing.T) {
+	corpusDir := filepath.Join(t.TempDir(), "FuzzFoo")
+	require.NoError(t, checkLayoutSidecar(corpusDir, LayoutOf[sidecarOld](), true, plainLayout))
+	require.NoError(t, checkLayoutSidecar(corpusDir, LayoutOf[sidecarNew](), false, plainLayout))
+	require.NoError(t, checkLayoutSidecar(corpusDir, LayoutOf[sidecarNew](), false, plainLayout))
+}
+
+func TestCheckLayoutSidecar_Invalid(t *testing.T) {
+	corpusDir := filepath.Join(t.TempDir(), "FuzzFoo")
+	require.NoError(t, os.WriteFile(LayoutSidecarPath(corpusDir), []byte("{"), 0o644))
+	assert.ErrorContains(t, checkLayoutSidecar(corpusDir, LayoutOf[sidecarOld](), false, plainLayout), LayoutSidecarPath(corpusDir))
+}
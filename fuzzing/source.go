@@ -0,0 +1,273 @@
+package fuzzing
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Source decodes primitive values from the raw bytes handed out by byte
+// oriented fuzzing engines, such as libFuzzer and go-fuzz.
+//
+// Numbers are read as fixed width little endian values, floats from their
+// IEEE 754 bits, and bools from the lowest bit of a byte. Strings and byte
+// slices are prefixed with their length as a uvarint, and cut short if fewer
+// bytes remain. Once the data is exhausted, every method returns the zero
+// value, so any input decodes to some value.
+type Source struct {
+	data     []byte
+	rejected bool
+}
+
+// NewSource returns a Source that decodes data.
+func NewSource(data []byte) *Source {
+	return &Source{data: data}
+}
+
+// Len returns the number of bytes that were not consumed yet.
+func (s *Source) Len() int {
+	return len(s.data)
+}
+
+// take consumes n bytes, padded with zeros if fewer than n bytes remain.
+func (s *Source) take(n int) []byte {
+	b := make([]byte, n)
+	s.data = s.data[copy(b, s.data):]
+	return b
+}
+
+// Bool decodes a bool from the lowest bit of a byte.
+func (s *Source) Bool() bool {
+	return s.Uint8()&1 == 1
+}
+
+// Uint8 decodes a uint8.
+func (s *Source) Uint8() uint8 {
+	return s.take(1)[0]
+}
+
+// Uint16 decodes a little endian uint16.
+func (s *Source) Uint16() uint16 {
+	return binary.LittleEndian.Uint16(s.take(2))
+}
+
+// Uint32 decodes a little endian uint32.
+func (s *Source) Uint32() uint32 {
+	return binary.LittleEndian.Uint32(s.take(4))
+}
+
+// Uint64 decodes a little endian uint64.
+func (s *Source) Uint64() uint64 {
+	return binary.LittleEndian.Uint64(s.take(8))
+}
+
+// Uint decodes a uint from 8 bytes.
+func (s *Source) Uint() uint {
+	return uint(s.Uint64())
+}
+
+// Int8 decodes an int8.
+func (s *Source) Int8() int8 {
+	return int8(s.Uint8())
+}
+
+// Int16 decodes a little endian int16.
+func (s *Source) Int16() int16 {
+	return int16(s.Uint16())
+}
+
+// Int32 decodes a little endian int32.
+func (s *Source) Int32() int32 {
+	return int32(s.Uint32())
+}
+
+// Int64 decodes a little endian int64.
+func (s *Source) Int64() int64 {
+	return int64(s.Uint64())
+}
+
+// Int decodes an int from 8 bytes.
+func (s *Source) Int() int {
+	return int(s.Int64())
+}
+
+// Float32 decodes a float32 from its little endian IEEE 754 bits.
+func (s *Source) Float32() float32 {
+	return math.Float32frombits(s.Uint32())
+}
+
+// Float64 decodes a float64 from its little endian IEEE 754 bits.
+func (s *Source) Float64() float64 {
+	return math.Float64frombits(s.Uint64())
+}
+
+// Bytes decodes a uvarint length followed by that many bytes.
+func (s *Source) Bytes() []byte {
+	n, size := binary.Uvarint(s.data)
+	if size <= 0 {
+		// The data is exhausted, or the length does not fit a uint64.
+		s.data = nil
+		return []byte{}
+	}
+	s.data = s.data[size:]
+	if n > uint64(len(s.data)) {
+		n = uint64(len(s.data))
+	}
+	b := append([]byte{}, s.data[:n]...)
+	s.data = s.data[n:]
+	return b
+}
+
+// Text decodes a string the same way as Bytes.
+func (s *Source) Text() string {
+	return string(s.Bytes())
+}
+
+// IntN decodes an int between 0 and n-1, from as few bytes as n needs. It
+// panics if n is not positive.
+func (s *Source) IntN(n int) int {
+	if n <= 0 {
+		panic("fuzzing: IntN needs a positive n")
+	}
+	return int(s.uintN(uint64(n)))
+}
+
+// uintN decodes a uint64 between 0 and n-1, or any uint64 if n is 0.
+func (s *Source) uintN(n uint64) uint64 {
+	switch {
+	case n == 0:
+		return s.Uint64()
+	case n <= 1<<8:
+		return uint64(s.Uint8()) % n
+	case n <= 1<<16:
+		return uint64(s.Uint16()) % n
+	case n <= 1<<32:
+		return uint64(s.Uint32()) % n
+	default:
+		return s.Uint64() % n
+	}
+}
+
+// appendUintN appends the encoding of v, a value between 0 and n-1, such that
+// uintN(n) decodes it back to v.
+func appendUintN(data []byte, v uint64, n uint64) []byte {
+	switch {
+	case n == 0 || n > 1<<32:
+		return binary.LittleEndian.AppendUint64(data, v)
+	case n <= 1<<8:
+		return append(data, uint8(v))
+	case n <= 1<<16:
+		return binary.LittleEndian.AppendUint16(data, uint16(v))
+	default:
+		return binary.LittleEndian.AppendUint32(data, uint32(v))
+	}
+}
+
+// Reject marks the input as invalid, for generators that cannot produce a
+// value from it. Fuzz skips rejected inputs like inputs rejected by a
+// precondition.
+func (s *Source) Reject() {
+	s.rejected = true
+}
+
+// Rejected reports whether Reject was called.
+func (s *Source) Rejected() bool {
+	return s.rejected
+}
+
+// value decodes a value of the layout slot type t.
+func (s *Source) value(t reflect.Type) reflect.Value {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		v.SetBool(s.Bool())
+	case reflect.Int:
+		v.SetInt(s.Int64())
+	case reflect.Int8:
+		v.SetInt(int64(s.Int8()))
+	case reflect.Int16:
+		v.SetInt(int64(s.Int16()))
+	case reflect.Int32:
+		v.SetInt(int64(s.Int32()))
+	case reflect.Int64:
+		v.SetInt(s.Int64())
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(s.Uint64())
+	case reflect.Uint8:
+		v.SetUint(uint64(s.Uint8()))
+	case reflect.Uint16:
+		v.SetUint(uint64(s.Uint16()))
+	case reflect.Uint32:
+		v.SetUint(uint64(s.Uint32()))
+	case reflect.Float32:
+		v.SetFloat(float64(s.Float32()))
+	case reflect.Float64:
+		v.SetFloat(s.Float64())
+	case reflect.Complex64:
+		v.SetComplex(complex(float64(s.Float32()), float64(s.Float32())))
+	case reflect.Complex128:
+		v.SetComplex(complex(s.Float64(), s.Float64()))
+	case reflect.String:
+		v.SetString(s.Text())
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Uint8 {
+			panic(fmt.Errorf("unsupported slot type %v", t))
+		}
+		v.SetBytes(s.Bytes())
+	default:
+		panic(fmt.Errorf("unsupported slot type %v", t))
+	}
+	return v
+}
+
+// appendValue appends the encoding of v, a value of a layout slot type, to
+// data, such that Source decodes it back to v.
+func appendValue(data []byte, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(data, 1)
+		}
+		return append(data, 0)
+	case reflect.Int8:
+		return append(data, uint8(v.Int()))
+	case reflect.Int16:
+		return binary.LittleEndian.AppendUint16(data, uint16(v.Int()))
+	case reflect.Int32:
+		return binary.LittleEndian.AppendUint32(data, uint32(v.Int()))
+	case reflect.Int, reflect.Int64:
+		return binary.LittleEndian.AppendUint64(data, uint64(v.Int()))
+	case reflect.Uint8:
+		return append(data, uint8(v.Uint()))
+	case reflect.Uint16:
+		return binary.LittleEndian.AppendUint16(data, uint16(v.Uint()))
+	case reflect.Uint32:
+		return binary.LittleEndian.AppendUint32(data, uint32(v.Uint()))
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return binary.LittleEndian.AppendUint64(data, v.Uint())
+	case reflect.Float32:
+		return binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		return binary.LittleEndian.AppendUint64(data, math.Float64bits(v.Float()))
+	case reflect.Complex64:
+		c := v.Complex()
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(real(c))))
+		return binary.LittleEndian.AppendUint32(data, math.Float32bits(float32(imag(c))))
+	case reflect.Complex128:
+		c := v.Complex()
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(real(c)))
+		return binary.LittleEndian.AppendUint64(data, math.Float64bits(imag(c)))
+	case reflect.String:
+		data = binary.AppendUvarint(data, uint64(v.Len()))
+		return append(data, v.String()...)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			panic(fmt.Errorf("unsupported slot type %v", v.Type()))
+		}
+		data = binary.AppendUvarint(data, uint64(v.Len()))
+		return append(data, v.Bytes()...)
+	default:
+		panic(fmt.Errorf("unsupported slot type %v", v.Type()))
+	}
+}
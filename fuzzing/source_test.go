@@ -0,0 +1,63 @@
+package fuzzing
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	source := NewSource([]byte{
+		0x03,
+		0x34, 0x12,
+		0xff, 0xff, 0xff, 0xff,
+		0x00, 0x00, 0x80, 0x3f,
+		0x03, 'f', 'o', 'o',
+		0x05, 'b', 'a',
+	})
+	assert.True(t, source.Bool())
+	assert.Equal(t, uint16(0x1234), source.Uint16())
+	assert.Equal(t, int32(-1), source.Int32())
+	assert.Equal(t, float32(1), source.Float32())
+	assert.Equal(t, "foo", source.Text())
+	// The length is cut short to the remaining bytes.
+	assert.Equal(t, []byte("ba"), source.Bytes())
+	assert.Equal(t, 0, source.Len())
+}
+
+func TestSource_Exhausted(t *testing.T) {
+	source := NewSource([]byte{0x01, 0x02})
+	// Missing bytes are zeros.
+	assert.Equal(t, uint32(0x0201), source.Uint32())
+	assert.False(t, source.Bool())
+	assert.Equal(t, 0, source.Int())
+	assert.Equal(t, 0.0, source.Float64())
+	assert.Equal(t, "", source.Text())
+	assert.Equal(t, []byte{}, source.Bytes())
+}
+
+func TestSource_RoundTrip(t *testing.T) {
+	values := []any{
+		true, false,
+		int(-42), int8(-8), int16(-16), int32(-32), int64(math.MinInt64),
+		uint(42), uint8(8), uint16(16), uint32(32), uint64(math.MaxUint64), uintptr(7),
+		float32(-1.5), math.Inf(-1), math.Copysign(0, -1),
+		complex64(complex(1, -2)), complex(3.5, math.Inf(1)),
+		"", "foo", string(make([]byte, 300)),
+		[]byte{}, []byte("bar"),
+	}
+	data := []byte{}
+	for _, value := range values {
+		data = appendValue(data, reflect.ValueOf(value))
+	}
+	source := NewSource(data)
+	for _, value := range values {
+		decoded := source.value(reflect.TypeOf(value)).Interface()
+		assert.Equal(t, value, decoded)
+		if f, ok := value.(float64); ok {
+			assert.Equal(t, math.Float64bits(f), math.Float64bits(decoded.(float64)))
+		}
+	}
+	assert.Equal(t, 0, source.Len())
+}
@@ -0,0 +1,129 @@
+package fuzzing
+
+import (
+	"bytes"
+	"io"
+)
+
+// Reader is an io.Reader that delivers its data with read patterns driven by
+// the fuzz input, for code that only misbehaves under pathological reads.
+// Every Read draws a byte from the input that picks one of:
+//
+//   - a full read, of as much data as fits, which may return io.EOF along
+//     with the last bytes,
+//   - a short read, of between 1 byte and as much data as fits,
+//   - a (0, nil) read,
+//   - an error: io.ErrUnexpectedEOF, or one of the errors passed to
+//     NewReader. Errors are sticky, so later reads return the same error.
+//
+// Once the input is exhausted, every read is a full read, so that reading to
+// the end terminates.
+type Reader struct {
+	data   []byte
+	source *Source
+	errs   []error
+	err    error
+}
+
+// NewReader returns a Reader that delivers data, with read patterns drawn
+// from s. Errors are chosen among io.ErrUnexpectedEOF and errs.
+func NewReader(data []byte, s *Source, errs ...error) *Reader {
+	return &Reader{data: data, source: s, errs: errs}
+}
+
+// ReaderGen generates Readers of the data generated by data, or of bytes from
+// the input if data is nil, with read patterns drawn from the rest of the
+// input. Bind it to io.Reader fields with WithGen or WithFieldGen.
+func ReaderGen(data Gen[[]byte], errs ...error) Gen[io.Reader] {
+	return func(s *Source) io.Reader {
+		if data == nil {
+			return NewReader(s.Bytes(), s, errs...)
+		}
+		return NewReader(data(s), s, errs...)
+	}
+}
+
+// Read implements io.Reader.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := min(len(p), len(r.data))
+	op := r.source.Uint8()
+	switch op % 4 {
+	case 1:
+		n = 1 + r.source.IntN(n)
+	case 2:
+		return 0, nil
+	case 3:
+		r.err = pickError(r.source, io.ErrUnexpectedEOF, r.errs)
+		return 0, r.err
+	}
+	copy(p, r.data[:n])
+	r.data = r.data[n:]
+	if len(r.data) == 0 && op&4 != 0 {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Writer is an io.Writer that keeps what is written to it, and fails writes
+// as driven by the fuzz input. Every Write draws a byte from the input that
+// picks either a full write, or a write of between 0 and all of the bytes
+// with an error: io.ErrShortWrite, or one of the errors passed to NewWriter.
+// Errors are sticky, so later writes return the same error. Once the input is
+// exhausted, every write is a full write.
+type Writer struct {
+	buf    bytes.Buffer
+	source *Source
+	errs   []error
+	err    error
+}
+
+// NewWriter returns a Writer that fails writes as drawn from s. Errors are
+// chosen among io.ErrShortWrite and errs.
+func NewWriter(s *Source, errs ...error) *Writer {
+	return &Writer{source: s, errs: errs}
+}
+
+// WriterGen generates Writers that fail writes as drawn from the input. Bind
+// it to io.Writer fields with WithGen or WithFieldGen.
+func WriterGen(errs ...error) Gen[io.Writer] {
+	return func(s *Source) io.Writer {
+		return NewWriter(s, errs...)
+	}
+}
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.source.Uint8()%2 == 0 {
+		return w.buf.Write(p)
+	}
+	n := w.source.IntN(len(p) + 1)
+	w.buf.Write(p[:n])
+	w.err = pickError(w.source, io.ErrShortWrite, w.errs)
+	return n, w.err
+}
+
+// Bytes returns the bytes written so far.
+func (w *Writer) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+// pickError draws one of def and errs from s.
+func pickError(s *Source, def error, errs []error) error {
+	i := s.IntN(len(errs) + 1)
+	if i == 0 {
+		return def
+	}
+	return errs[i-1]
+}
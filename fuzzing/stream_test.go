@@ -0,0 +1,126 @@
+package fuzzing
+
+import (
+	"errors"
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestReader_Read(t *testing.T) {
+	// A short read of 1+1 bytes, a (0, nil) read, then a full read of the
+	// rest with io.EOF.
+	r := NewReader([]byte("hello"), NewSource([]byte{1, 1, 2, 4}))
+	p := make([]byte, 4)
+	n, err := r.Read(p)
+	assert.Equal(t, 2, n)
+	assert.NoError(t, err)
+	assert.Equal(t, "he", string(p[:n]))
+	n, err = r.Read(p)
+	assert.Equal(t, 0, n)
+	assert.NoError(t, err)
+	n, err = r.Read(p)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "llo", string(p[:n]))
+	n, err = r.Read(p)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReader_Errors(t *testing.T) {
+	errCustom := errors.New("custom")
+	r := NewReader([]byte("hello"), NewSource([]byte{0, 3, 1}), errCustom)
+	p := make([]byte, 2)
+	n, err := r.Read(p)
+	assert.Equal(t, 2, n)
+	assert.NoError(t, err)
+	_, err = r.Read(p)
+	assert.Equal(t, errCustom, err)
+	_, err = r.Read(p)
+	assert.Equal(t, errCustom, err)
+
+	r = NewReader([]byte("hello"), NewSource([]byte{3}))
+	_, err = io.ReadAll(r)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestReader_ExhaustedInput(t *testing.T) {
+	data, err := io.ReadAll(NewReader([]byte("hello"), NewSource(nil)))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestWriter_Write(t *testing.T) {
+	errCustom := errors.New("custom")
+	w := NewWriter(NewSource([]byte{0, 1, 2, 1}), errCustom)
+	n, err := w.Write([]byte("hello"))
+	assert.Equal(t, 5, n)
+	assert.NoError(t, err)
+	n, err = w.Write([]byte("world"))
+	assert.Equal(t, 2, n)
+	assert.Equal(t, errCustom, err)
+	n, err = w.Write([]byte("again"))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, errCustom, err)
+	assert.Equal(t, "hellowo", string(w.Bytes()))
+
+	w = NewWriter(NewSource([]byte{1, 0, 0}))
+	_, err = w.Write([]byte("hello"))
+	assert.Equal(t, io.ErrShortWrite, err)
+	assert.Empty(t, w.Bytes())
+}
+
+type streamInput struct {
+	R io.Reader
+	W io.Writer
+}
+
+func TestFuzz_ReaderGen(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+	mockF.EXPECT().Name().Return("FuzzFoo").AnyTimes()
+
+	var target func(*testing.T, []byte, []byte)
+	mockF.EXPECT().Fuzz(gomock.Any()).Do(func(fuzzTarget any) {
+		target = fuzzTarget.(func(*testing.T, []byte, []byte))
+	})
+	var copied []string
+	Fuzz(mockF, func(t *testing.T, input streamInput) {
+		_, err := io.Copy(input.W, input.R)
+		assert.NoError(t, err)
+		copied = append(copied, string(input.W.(*Writer).Bytes()))
+	}, WithGen(ReaderGen(nil)), WithGen(WriterGen()))
+
+	target(t, []byte{5, 'h', 'e', 'l', 'l', 'o', 1, 1, 2}, []byte{})
+	target(t, []byte{}, []byte{})
+	assert.Equal(t, []string{"hello", ""}, copied)
+}
+
+type streamReportInput struct {
+	In  io.Reader
+	Out io.Writer
+	N   int
+}
+
+func TestDescribeFailingInput_Streams(t *testing.T) {
+	gens := newConfig([]Option{WithGen(ReaderGen(nil)), WithGen(WriterGen())}).gens
+	slots := []reflect.Value{reflect.ValueOf([]byte{2, 'h', 'i', 0}), reflect.ValueOf([]byte{1, 0}), reflect.ValueOf(3)}
+	builder := buildAnyTraverser{fields: slots, gens: gens}
+	decoded := builder.traverseType(reflect.TypeFor[streamReportInput]())
+	require.IsType(t, &Reader{}, decoded.Interface().(streamReportInput).In)
+
+	reported := reportedValue(reflect.TypeFor[streamReportInput](), slots, gens, decoded, builder.generated)
+	assert.Equal(t, streamReportInput{N: 3}, reported.Interface())
+	description := describeFailingInput("", generatedInput{input: reported.Interface(), fields: builder.generated})
+	assert.Equal(t, "decoded input of type fuzzing.streamReportInput:\n\tIn = nil\n\tOut = nil\n\tN = 3\n"+
+		"as Go literal:\n\tstreamReportInput{N: 3}\n"+
+		"generated fields, zero above, by the input of their generators:\n"+
+		"\tIn = []byte(\"\\x02hi\\x00\")\n"+
+		"\tOut = []byte(\"\\x01\\x00\")", description)
+}
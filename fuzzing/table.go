@@ -0,0 +1,179 @@
+package fuzzing
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// AddAll adds every value of seq to the corpus, as if by Add. Use
+// slices.Values to add a slice.
+func AddAll[T any](f TestingF, seq iter.Seq[T], opts ...Option) {
+	for t := range seq {
+		Add(f, t, opts...)
+	}
+}
+
+// AddNamed adds t to the corpus, as if by Add. If the fuzz target fails for
+// t while running the seed corpus, name is reported together with the
+// decoded input.
+//
+// The Go engine only numbers seeds, so names are tracked by the order seeds
+// were added in. Seeds added with f.Add directly shift the numbers. Such
+// shifts are detected by comparing the input with the named seed, and the
+// name is then left out rather than misattributed, so add every seed through
+// this package to keep the names.
+func AddNamed[T any](f TestingF, name string, t T, opts ...Option) {
+	fields, err := flatten(t, newConfig(opts))
+	if err != nil {
+		f.Fatalf("fuzzing: %v", err)
+		return
+	}
+	addFields(f, name, fields)
+}
+
+// AddTable adds the input of every case of a table driven test to the corpus,
+// so that the unit test and the fuzz test share a single source of examples.
+// input extracts the input from a case. name, which may be nil, extracts the
+// name of the case, as for AddNamed.
+func AddTable[C any, T any](f TestingF, cases []C, input func(C) T, name func(C) string, opts ...Option) {
+	for i, c := range cases {
+		caseName := fmt.Sprintf("case %d", i)
+		if name != nil {
+			caseName = name(c)
+		}
+		AddNamed(f, caseName, input(c), opts...)
+	}
+}
+
+// flatten returns the flattened fields of t, with an empty []byte for every
+// field bound to a generator of cfg, or an error if cfg does not apply to T.
+func flatten[T any](t T, cfg *config) ([]any, error) {
+	if err := cfg.checkSeedType(reflect.TypeFor[T]()); err != nil {
+		return nil, err
+	}
+	fieldsTraverser := anyToFieldsTraverser{gens: cfg.gens}
+	fieldsTraverser.traverseValue(reflect.ValueOf(t))
+	return fieldsTraverser.fields, nil
+}
+
+// seedRegistry remembers the seeds added to a fuzz test. The Go fuzzing
+// engine runs the seeds added by f.Add as subtests named seed#N, in the order
+// they were added.
+type seedRegistry struct {
+	count int
+	seedNames
+	// slotTypes are the types of the flattened fields of every seed.
+	slotTypes [][]reflect.Type
+}
+
+// seedNames are the names of the seeds added to a fuzz test by seed number,
+// with the flattened fields of the named seeds.
+type seedNames struct {
+	names  map[int]string
+	fields map[int][]any
+}
+
+var seedRegistries = struct {
+	sync.Mutex
+	byF map[TestingF]*seedRegistry
+}{byF: map[TestingF]*seedRegistry{}}
+
+// addFields adds the flattened fields of a seed to the corpus of f. Every
+// seed must be added through addFields to keep the seed numbers right.
+func addFields(f TestingF, name string, fields []any) {
+	seedRegistries.Lock()
+	registry, ok := seedRegistries.byF[f]
+	if !ok {
+		registry = &seedRegistry{seedNames: seedNames{names: map[int]string{}, fields: map[int][]any{}}}
+		seedRegistries.byF[f] = registry
+		if cleaner, ok := f.(interface{ Cleanup(func()) }); ok {
+			// Forget the seeds of fuzz tests that never call Fuzz.
+			cleaner.Cleanup(func() {
+				seedRegistries.Lock()
+				defer seedRegistries.Unlock()
+				if seedRegistries.byF[f] == registry {
+					delete(seedRegistries.byF, f)
+				}
+			})
+		}
+	}
+	if name != "" {
+		registry.names[registry.count] = name
+		registry.fields[registry.count] = fields
+	}
+	slotTypes := make([]reflect.Type, 0, len(fields))
+	for _, field := range fields {
+		slotTypes = append(slotTypes, reflect.TypeOf(field))
+	}
+	registry.slotTypes = append(registry.slotTypes, slotTypes)
+	registry.count++
+	seedRegistries.Unlock()
+	f.Add(fields...)
+}
+
+// takeSeedNames returns the names of the seeds added to f, and forgets them,
+// as no seeds can be added once fuzzing started.
+func takeSeedNames(f TestingF) seedNames {
+	seedRegistries.Lock()
+	defer seedRegistries.Unlock()
+	registry, ok := seedRegistries.byF[f]
+	delete(seedRegistries.byF, f)
+	if !ok {
+		return seedNames{}
+	}
+	return registry.seedNames
+}
+
+// checkSeedSlots returns an error if a seed added to f does not match the
+// layout slotTypes of the fuzzed type, such as a seed added without the
+// options that bind generators.
+func checkSeedSlots(f TestingF, slotTypes []reflect.Type) error {
+	seedRegistries.Lock()
+	defer seedRegistries.Unlock()
+	registry, ok := seedRegistries.byF[f]
+	if !ok {
+		return nil
+	}
+	for i, seedSlotTypes := range registry.slotTypes {
+		if !slices.Equal(seedSlotTypes, slotTypes) {
+			seed := fmt.Sprintf("seed#%d", i)
+			if name := registry.names[i]; name != "" {
+				seed += fmt.Sprintf(" (%s)", name)
+			}
+			return fmt.Errorf("%s does not match the layout of the fuzzed type, add seeds with fuzzing.Add and the options passed to Fuzz", seed)
+		}
+	}
+	return nil
+}
+
+// seedName returns the name of the seed that the (sub)test testName runs
+// with the flattened fields args, if it has one. The name is left out if args
+// are not the fields of the named seed, as seeds added with f.Add directly
+// shift the seed numbers.
+func seedName(seeds seedNames, testName string, args []reflect.Value) string {
+	_, seed, ok := strings.Cut(testName[strings.LastIndex(testName, "/")+1:], "seed#")
+	if !ok {
+		return ""
+	}
+	i, err := strconv.Atoi(seed)
+	if err != nil {
+		return ""
+	}
+	name, ok := seeds.names[i]
+	if !ok {
+		return ""
+	}
+	fields := make([]any, 0, len(args))
+	for _, arg := range args {
+		fields = append(fields, arg.Interface())
+	}
+	if fmt.Sprintf("%#v", fields) != fmt.Sprintf("%#v", seeds.fields[i]) {
+		return ""
+	}
+	return name
+}
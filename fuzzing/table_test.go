@@ -0,0 +1,122 @@
+package fuzzing
+
+import (
+	"github.com/hugoklepsch/go-fuzz-all/internal/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+type tableIn struct {
+	S string
+	I int
+}
+
+type tableCase struct {
+	name string
+	in   tableIn
+	want int
+}
+
+var tableCases = []tableCase{
+	{"empty", tableIn{}, 0},
+	{"foo", tableIn{S: "foo", I: 1}, 4},
+}
+
+func TestAddAll(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	gomock.InOrder(
+		mockF.EXPECT().Add("a", 1),
+		mockF.EXPECT().Add("b", 2),
+	)
+	AddAll(mockF, slices.Values([]tableIn{{"a", 1}, {"b", 2}}))
+	assert.Empty(t, takeSeedNames(mockF).names)
+}
+
+func TestAddTable(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	gomock.InOrder(
+		mockF.EXPECT().Add("x", 0),
+		mockF.EXPECT().Add("", 0),
+		mockF.EXPECT().Add("foo", 1),
+	)
+	Add(mockF, tableIn{S: "x"})
+	AddTable(mockF, tableCases, func(c tableCase) tableIn { return c.in }, func(c tableCase) string { return c.name })
+	assert.Equal(t, map[int]string{1: "empty", 2: "foo"}, takeSeedNames(mockF).names)
+	assert.Nil(t, takeSeedNames(mockF).names, "names are forgotten once taken")
+}
+
+func TestAddTable_NoNames(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	mockF.EXPECT().Add(gomock.Any(), gomock.Any()).Times(2)
+	AddTable(mockF, []tableIn{{}, {}}, func(in tableIn) tableIn { return in }, nil)
+	assert.Equal(t, map[int]string{0: "case 0", 1: "case 1"}, takeSeedNames(mockF).names)
+}
+
+func TestSeedName(t *testing.T) {
+	seeds := seedNames{names: map[int]string{1: "foo"}, fields: map[int][]any{1: {"foo", 1}}}
+	args := []reflect.Value{reflect.ValueOf("foo"), reflect.ValueOf(1)}
+	assert.Equal(t, "foo", seedName(seeds, "FuzzFoo/seed#1", args))
+	assert.Equal(t, "", seedName(seeds, "FuzzFoo/seed#0", args))
+	assert.Equal(t, "", seedName(seeds, "FuzzFoo/0123456789abcdef", args))
+	assert.Equal(t, "", seedName(seeds, "FuzzFoo", args))
+	assert.Equal(t, "", seedName(seedNames{}, "FuzzFoo/seed#1", args))
+}
+
+func TestSeedName_InterleavedAdd(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockF := mocks.NewMockTestingF(mockCtrl)
+
+	mockF.EXPECT().Add(gomock.Any(), gomock.Any()).Times(3)
+	AddNamed(mockF, "first", tableIn{S: "a"})
+	// Added directly, so the registry does not count it.
+	mockF.Add("b", 2)
+	AddNamed(mockF, "second", tableIn{S: "c"})
+	seeds := takeSeedNames(mockF)
+
+	assert.Equal(t, "first", seedName(seeds, "FuzzFoo/seed#0", []reflect.Value{reflect.ValueOf("a"), reflect.ValueOf(0)}))
+	// seed#1 is the seed added directly, not the second named seed.
+	assert.Equal(t, "", seedName(seeds, "FuzzFoo/seed#1", []reflect.Value{reflect.ValueOf("b"), reflect.ValueOf(2)}))
+	assert.Equal(t, "", seedName(seeds, "FuzzFoo/seed#2", []reflect.Value{reflect.ValueOf("c"), reflect.ValueOf(0)}))
+}
+
+type cleanupF struct {
+	*mocks.MockTestingF
+	cleanups []func()
+}
+
+func (f *cleanupF) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func TestAddNamed_ForgottenOnCleanup(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	f := &cleanupF{MockTestingF: mocks.NewMockTestingF(mockCtrl)}
+
+	f.EXPECT().Add(gomock.Any(), gomock.Any())
+	AddNamed(f, "first", tableIn{S: "a"})
+	require.Len(t, f.cleanups, 1)
+	f.cleanups[0]()
+	seedRegistries.Lock()
+	_, ok := seedRegistries.byF[f]
+	seedRegistries.Unlock()
+	assert.False(t, ok)
+}
+
+func TestDescribeFailingInput_SeedName(t *testing.T) {
+	assert.Contains(t, describeFailingInput("foo", tableIn{}), "seed \"foo\"\ndecoded input of type fuzzing.tableIn:")
+}
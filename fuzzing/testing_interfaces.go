@@ -5,7 +5,9 @@ import "testing"
 //go:generate mockgen -destination ../internal/mocks/testinlFMock.go -package mocks github.com/hugoklepsch/go-fuzz-all/fuzzing TestingF
 type TestingF interface {
 	Add(...any)
+	Fatalf(format string, args ...any)
 	Fuzz(any)
+	Name() string
 }
 
 //go:generate mockgen -destination ../internal/mocks/testingTMock.go -package mocks github.com/hugoklepsch/go-fuzz-all/fuzzing TestingT
@@ -0,0 +1,235 @@
+package fuzzing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// ToolRequestEnv is the environment variable that holds the path of the
+// ToolRequest read by ToolHelper.
+const ToolRequestEnv = "FUZZ_ALL_TOOL_REQUEST"
+
+// ToolRequest is written by cmd/fuzz-all and executed by ToolHelper inside the
+// test binary of the package that declares the fuzzed type.
+type ToolRequest struct {
+	// Command is the fuzz-all subcommand to execute, such as "decode",
+	// "layout", "export", "prune" or "canonicalize".
+	Command string `json:"command"`
+	// Format is the output format, "json" or "go" for the "decode" command,
+	// and "fields" or "bytes" for the "export" command.
+	Format string `json:"format,omitempty"`
+	// Paths are the corpus files or directories to operate on.
+	Paths []string `json:"paths,omitempty"`
+	// Dir is the directory the "export" command writes to.
+	Dir string `json:"dir,omitempty"`
+	// DryRun makes the "prune" command report what it would remove, without
+	// removing it.
+	DryRun bool `json:"dryRun,omitempty"`
+	// Output is the file the result is written to.
+	Output string `json:"output"`
+}
+
+// ToolHelper executes the ToolRequest named by the ToolRequestEnv environment
+// variable for the type T. It is called from the test helper that cmd/fuzz-all
+// generates, and skips the test when it is run without a request. opts are the
+// options passed to Fuzz, as generators and constructors change the layout.
+func ToolHelper[T any](t *testing.T, opts ...Option) {
+	requestPath := os.Getenv(ToolRequestEnv)
+	if requestPath == "" {
+		t.Skip(ToolRequestEnv + " is not set")
+	}
+	data, err := os.ReadFile(requestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := ToolRequest{}
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := runTool[T](request, out, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(request.Output, out.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runTool[T any](request ToolRequest, out *bytes.Buffer, opts ...Option) error {
+	cfg := newConfig(opts)
+	decodeType, err := cfg.decodeType(reflect.TypeFor[T]())
+	if err != nil {
+		return err
+	}
+	switch request.Command {
+	case "decode":
+		return decodeTool(request, out, cfg, decodeType)
+	case "export":
+		return exportTool(request, out, cfg, decodeType)
+	case "prune":
+		report, err := PruneCorpus[T](request.Paths, request.DryRun, opts...)
+		for _, removed := range report.Removed {
+			fmt.Fprintf(out, "removed %s: %s\n", removed.Path, removed.Reason)
+		}
+		fmt.Fprintf(out, "kept %d, removed %d\n", len(report.Kept), len(report.Removed))
+		return err
+	case "canonicalize":
+		for _, path := range request.Paths {
+			rewritten, err := CanonicalizeCorpus[T](path, opts...)
+			for _, p := range rewritten {
+				fmt.Fprintf(out, "canonicalized %s\n", p)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "layout":
+		data, err := json.MarshalIndent(layoutOf(decodeType, cfg.gens), "", "\t")
+		if err != nil {
+			return err
+		}
+		out.Write(data)
+		out.WriteByte('\n')
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", request.Command)
+	}
+}
+
+// decodeTool writes the entries of the corpora decoded into values of t, or
+// into the constructor calls that build them.
+func decodeTool(request ToolRequest, out *bytes.Buffer, cfg *config, t reflect.Type) error {
+	switch request.Format {
+	case "json", "go", "":
+	default:
+		return fmt.Errorf("unknown format %q", request.Format)
+	}
+	if len(cfg.gens) > 0 {
+		return fmt.Errorf("decode does not support generators, as the fields bound to them hold generator input rather than values")
+	}
+	for _, path := range request.Paths {
+		entries, err := readCorpus(path, t, nil)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			var value any
+			if entry.err == nil {
+				decoded, _ := decodeSlots(t, nil, entry.vals)
+				value = decoded.Interface()
+				if cfg.constructor != nil {
+					value = constructorCall{c: cfg.constructor, args: decoded}
+				}
+			}
+			if err := writeCorpusEntry(out, request.Format, entry.path, value, entry.err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exportTool writes every decodable entry of the corpora in the field path
+// keyed format, or the format of ByteTarget, and lists the written files.
+func exportTool(request ToolRequest, out *bytes.Buffer, cfg *config, t reflect.Type) error {
+	var write func(dir string, vals []any) (string, error)
+	switch request.Format {
+	case "fields", "":
+		if len(cfg.gens) > 0 {
+			return fmt.Errorf("export -format fields does not support generators, as fields files hold values rather than generator input")
+		}
+		write = func(dir string, vals []any) (string, error) {
+			value, err := decodeSlots(t, nil, vals)
+			if err != nil {
+				return "", err
+			}
+			return writeFieldsFile(dir, value, nil)
+		}
+	case "bytes":
+		if cfg.constructor != nil {
+			return fmt.Errorf("export -format bytes does not support constructors, as ByteTarget does not")
+		}
+		write = func(dir string, vals []any) (string, error) {
+			data := []byte{}
+			for _, val := range canonicalSlots(t, cfg.gens, vals) {
+				data = appendValue(data, reflect.ValueOf(val))
+			}
+			return writeBytesFile(dir, data)
+		}
+	default:
+		return fmt.Errorf("unknown format %q", request.Format)
+	}
+	for _, path := range request.Paths {
+		entries, err := readCorpus(path, t, cfg.gens)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.err != nil {
+				fmt.Fprintf(out, "skipped %v\n", entry.err)
+				continue
+			}
+			written, err := write(request.Dir, entry.vals)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "exported %s to %s\n", entry.path, written)
+		}
+	}
+	return nil
+}
+
+type jsonCorpusEntry struct {
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// writeCorpusEntry writes the corpus entry at path, decoded into value, a
+// value or a constructorCall, or err.
+func writeCorpusEntry(out *bytes.Buffer, format string, path string, value any, err error) error {
+	call, isCall := value.(constructorCall)
+	switch format {
+	case "go":
+		fmt.Fprintf(out, "// %s\n", path)
+		if err != nil {
+			fmt.Fprintf(out, "// error: %v\n", err)
+			return nil
+		}
+		if isCall {
+			_, code := call.describe()
+			fmt.Fprintf(out, "%s\n", code)
+			return nil
+		}
+		fmt.Fprintf(out, "%s\n", GoLiteral(value))
+		return nil
+	default:
+		jsonEntry := jsonCorpusEntry{Path: path}
+		if err == nil {
+			if isCall {
+				value = call.args.Interface()
+			}
+			data, marshalErr := json.Marshal(value)
+			if marshalErr != nil {
+				err = marshalErr
+			}
+			jsonEntry.Value = data
+		}
+		if err != nil {
+			jsonEntry.Value = nil
+			jsonEntry.Error = err.Error()
+		}
+		line, err := json.Marshal(jsonEntry)
+		if err != nil {
+			return err
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+		return nil
+	}
+}
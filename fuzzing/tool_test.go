@@ -0,0 +1,179 @@
+package fuzzing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type toolFoo struct {
+	S string
+	P *int
+}
+
+func TestRunTool_DecodeJSON(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpusFile(t, dir, "a", "go test fuzz v1\nstring(\"foo\")\nbool(true)\nint(42)\n")
+	writeCorpusFile(t, dir, "b", "go test fuzz v1\nstring(\"bar\")\n")
+
+	out := &bytes.Buffer{}
+	err := runTool[toolFoo](ToolRequest{Command: "decode", Format: "json", Paths: []string{dir}}, out)
+	require.NoError(t, err)
+	expected := fmt.Sprintf(`{"path":%q,"value":{"S":"foo","P":42}}`+"\n"+
+		`{"path":%q,"error":"%s: fuzzing.toolFoo has 3 fields, got 1 values"}`+"\n",
+		filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "b"))
+	assert.Equal(t, expected, out.String())
+}
+
+func TestRunTool_DecodeGo(t *testing.T) {
+	dir := t.TempDir()
+	path := writeCorpusFile(t, dir, "a", "go test fuzz v1\nstring(\"foo\")\nbool(true)\nint(42)\n")
+
+	out := &bytes.Buffer{}
+	err := runTool[toolFoo](ToolRequest{Command: "decode", Format: "go", Paths: []string{path}}, out)
+	require.NoError(t, err)
+	assert.Equal(t, "// "+path+"\ntoolFoo{S: \"foo\", P: ptr(42)}\n", out.String())
+}
+
+func TestRunTool_Unknown(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.Error(t, runTool[toolFoo](ToolRequest{Command: "unknown"}, out))
+	assert.Error(t, runTool[toolFoo](ToolRequest{Command: "decode", Format: "xml", Paths: []string{t.TempDir()}}, out))
+}
+
+func TestRunTool_Layout(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, runTool[toolFoo](ToolRequest{Command: "layout"}, out))
+	layout := Layout{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &layout))
+	assert.Equal(t, LayoutOf[toolFoo](), layout)
+}
+
+func TestRunTool_Prune(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpusFile(t, dir, "a", "go test fuzz v1\nstring(\"foo\")\nbool(false)\nint(0)\n")
+	b := writeCorpusFile(t, dir, "b", "go test fuzz v1\nstring(\"foo\")\nbool(false)\nint(42)\n")
+
+	out := &bytes.Buffer{}
+	require.NoError(t, runTool[toolFoo](ToolRequest{Command: "prune", Paths: []string{dir}, DryRun: true}, out))
+	assert.Equal(t, "removed "+b+": duplicate of "+filepath.Join(dir, "a")+"\nkept 1, removed 1\n", out.String())
+}
+
+func TestRunTool_Canonicalize(t *testing.T) {
+	dir := t.TempDir()
+	writeCorpusFile(t, dir, "a", "go test fuzz v1\nstring(\"foo\")\nbool(false)\nint(0)\n")
+	b := writeCorpusFile(t, dir, "b", "go test fuzz v1\nstring(\"foo\")\nbool(false)\nint(42)\n")
+
+	out := &bytes.Buffer{}
+	require.NoError(t, runTool[toolFoo](ToolRequest{Command: "canonicalize", Paths: []string{dir}}, out))
+	assert.Equal(t, "canonicalized "+b+"\n", out.String())
+}
+
+type toolGenFoo struct {
+	R io.Reader
+	P *int
+}
+
+func TestRunTool_LayoutWithGen(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, runTool[toolGenFoo](ToolRequest{Command: "layout"}, out, WithGen(ReaderGen(nil))))
+	layout := Layout{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &layout))
+	assert.Equal(t, layoutOf(reflect.TypeFor[toolGenFoo](), newConfig([]Option{WithGen(ReaderGen(nil))}).gens), layout)
+	assert.Equal(t, LayoutField{Path: "R", Type: "[]uint8"}, layout.Fields[0])
+}
+
+func TestRunTool_DecodeWithGen(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := runTool[toolGenFoo](ToolRequest{Command: "decode", Paths: []string{t.TempDir()}}, out, WithGen(ReaderGen(nil)))
+	assert.ErrorContains(t, err, "decode does not support generators")
+}
+
+func TestRunTool_ExportWithGen(t *testing.T) {
+	dir := t.TempDir()
+	outDir := t.TempDir()
+	writeCorpusFile(t, dir, "a", "go test fuzz v1\n[]byte(\"abc\")\nbool(false)\nint(42)\n")
+
+	out := &bytes.Buffer{}
+	err := runTool[toolGenFoo](ToolRequest{Command: "export", Format: "fields", Paths: []string{dir}, Dir: outDir}, out, WithGen(ReaderGen(nil)))
+	assert.ErrorContains(t, err, "export -format fields does not support generators")
+
+	out.Reset()
+	err = runTool[toolGenFoo](ToolRequest{Command: "export", Format: "bytes", Paths: []string{dir}, Dir: outDir}, out, WithGen(ReaderGen(nil)))
+	require.NoError(t, err)
+	written, err := filepath.Glob(filepath.Join(outDir, "*"))
+	require.NoError(t, err)
+	require.Len(t, written, 1)
+	data, err := os.ReadFile(written[0])
+	require.NoError(t, err)
+	// The generator input, then the nil pointer with a canonical payload.
+	assert.Equal(t, append([]byte{3, 'a', 'b', 'c', 0}, make([]byte, 8)...), data)
+}
+
+func TestRunTool_PruneWithGen(t *testing.T) {
+	dir := t.TempDir()
+	a := writeCorpusFile(t, dir, "a", "go test fuzz v1\n[]byte(\"abc\")\nbool(false)\nint(0)\n")
+
+	out := &bytes.Buffer{}
+	require.Error(t, runTool[toolGenFoo](ToolRequest{Command: "prune", Paths: []string{dir}}, out))
+	assert.FileExists(t, a)
+
+	out.Reset()
+	require.NoError(t, runTool[toolGenFoo](ToolRequest{Command: "prune", Paths: []string{dir}}, out, WithGen(ReaderGen(nil))))
+	assert.Equal(t, "kept 1, removed 0\n", out.String())
+}
+
+func TestRunTool_DecodeWithConstructor(t *testing.T) {
+	c, err := NewConstructor[*ctorAccount](newCtorAccount)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	path := writeCorpusFile(t, dir, "a", "go test fuzz v1\nstring(\"alice\")\nbool(true)\nint(5)\n")
+
+	out := &bytes.Buffer{}
+	require.NoError(t, runTool[*ctorAccount](ToolRequest{Command: "decode", Format: "go", Paths: []string{dir}}, out, WithConstructor(c)))
+	assert.Equal(t, "// "+path+"\nfuzzing.newCtorAccount(\"alice\", ptr(5))\n", out.String())
+
+	out.Reset()
+	require.NoError(t, runTool[*ctorAccount](ToolRequest{Command: "decode", Format: "json", Paths: []string{dir}}, out, WithConstructor(c)))
+	assert.Equal(t, fmt.Sprintf(`{"path":%q,"value":{"Arg0":"alice","Arg1":5}}`+"\n", path), out.String())
+}
+
+func TestRunTool_ExportWithConstructor(t *testing.T) {
+	c, err := NewConstructor[*ctorAccount](newCtorAccount)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	outDir := t.TempDir()
+	writeCorpusFile(t, dir, "a", "go test fuzz v1\nstring(\"alice\")\nbool(true)\nint(5)\n")
+
+	out := &bytes.Buffer{}
+	err = runTool[*ctorAccount](ToolRequest{Command: "export", Format: "bytes", Paths: []string{dir}, Dir: outDir}, out, WithConstructor(c))
+	assert.ErrorContains(t, err, "export -format bytes does not support constructors")
+
+	require.NoError(t, runTool[*ctorAccount](ToolRequest{Command: "export", Format: "fields", Paths: []string{dir}, Dir: outDir}, out, WithConstructor(c)))
+	written, err := filepath.Glob(filepath.Join(outDir, "*"+fieldsFileExt))
+	require.NoError(t, err)
+	require.Len(t, written, 1)
+	args, err := readFieldsFile(written[0], c.c.args)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", args.Field(0).Interface())
+	assert.Equal(t, 5, *args.Field(1).Interface().(*int))
+}
+
+func TestRunTool_LayoutWithConstructor(t *testing.T) {
+	c, err := NewConstructor[*ctorAccount](newCtorAccount)
+	require.NoError(t, err)
+	out := &bytes.Buffer{}
+	require.NoError(t, runTool[*ctorAccount](ToolRequest{Command: "layout"}, out, WithConstructor(c)))
+	layout := Layout{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &layout))
+	assert.Equal(t, layoutOf(c.c.args, nil), layout)
+
+	assert.ErrorContains(t, runTool[int](ToolRequest{Command: "layout"}, out, WithConstructor(c)), "constructor fuzzing.newCtorAccount returns")
+}
@@ -0,0 +1,137 @@
+// Package recording samples values of a type in production code and appends
+// them to a JSON lines file, to be used as a seed corpus by
+// fuzzing.AddRecording. It does not depend on the testing package.
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"sync"
+)
+
+// ErrClosed is returned by Record after the Recorder was closed.
+var ErrClosed = errors.New("recording: recorder is closed")
+
+// Option configures a Recorder.
+type Option func(*options)
+
+type options struct {
+	sampleRate float64
+	maxRecords int
+}
+
+// WithSampleRate records only the given fraction, between 0 and 1, of the
+// values passed to Record. The default is to record every value.
+func WithSampleRate(rate float64) Option {
+	return func(o *options) {
+		o.sampleRate = rate
+	}
+}
+
+// WithMaxRecords stops recording once the file holds n values, including
+// values recorded by earlier processes. The default is 1000. A value of 0 or
+// less means no limit.
+func WithMaxRecords(n int) Option {
+	return func(o *options) {
+		o.maxRecords = n
+	}
+}
+
+// Recorder appends sampled values of type T to a JSON lines file. It is safe
+// for concurrent use.
+type Recorder[T any] struct {
+	opts options
+
+	mu      sync.Mutex
+	file    *os.File
+	records int
+}
+
+// New opens, or creates, the JSON lines file at path for recording.
+func New[T any](path string, opts ...Option) (*Recorder[T], error) {
+	o := options{
+		sampleRate: 1,
+		maxRecords: 1000,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	records, err := countLines(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder[T]{
+		opts:    o,
+		file:    file,
+		records: records,
+	}, nil
+}
+
+// Record appends v to the file, unless it is not sampled or the file is full.
+func (r *Recorder[T]) Record(v T) error {
+	if r.opts.sampleRate < 1 && rand.Float64() >= r.opts.sampleRate {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return ErrClosed
+	}
+	if r.full() {
+		return nil
+	}
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	r.records++
+	return nil
+}
+
+// full reports whether the file holds the maximum number of values. The caller
+// must hold r.mu.
+func (r *Recorder[T]) full() bool {
+	return r.opts.maxRecords > 0 && r.records >= r.opts.maxRecords
+}
+
+// Close closes the file. Values recorded afterwards are rejected.
+func (r *Recorder[T]) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return ErrClosed
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func countLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	lines := 0
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			lines++
+		}
+	}
+	return lines, scanner.Err()
+}
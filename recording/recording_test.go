@@ -0,0 +1,85 @@
+package recording
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type Foo struct {
+	S string
+	I int
+}
+
+func readLines(t *testing.T, path string) []string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestRecorder_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo.jsonl")
+	r, err := New[Foo](path)
+	require.NoError(t, err)
+	require.NoError(t, r.Record(Foo{S: "foo", I: 1}))
+	require.NoError(t, r.Record(Foo{S: "bar", I: 2}))
+	require.NoError(t, r.Close())
+
+	assert.Equal(t, []string{`{"S":"foo","I":1}`, `{"S":"bar","I":2}`}, readLines(t, path))
+	assert.ErrorIs(t, r.Record(Foo{}), ErrClosed)
+}
+
+func TestRecorder_MaxRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo.jsonl")
+	r, err := New[Foo](path, WithMaxRecords(3))
+	require.NoError(t, err)
+	require.NoError(t, r.Record(Foo{I: 1}))
+	require.NoError(t, r.Record(Foo{I: 2}))
+	require.NoError(t, r.Close())
+
+	// The records of earlier processes count towards the limit.
+	r, err = New[Foo](path, WithMaxRecords(3))
+	require.NoError(t, err)
+	require.NoError(t, r.Record(Foo{I: 3}))
+	require.NoError(t, r.Record(Foo{I: 4}))
+	require.NoError(t, r.Close())
+
+	assert.Equal(t, []string{`{"S":"","I":1}`, `{"S":"","I":2}`, `{"S":"","I":3}`}, readLines(t, path))
+}
+
+func TestRecorder_SampleRate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo.jsonl")
+	r, err := New[Foo](path, WithSampleRate(0))
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, r.Record(Foo{I: i}))
+	}
+	require.NoError(t, r.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, data)
+}
+
+func TestRecorder_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo.jsonl")
+	r, err := New[Foo](path, WithMaxRecords(50))
+	require.NoError(t, err)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				assert.NoError(t, r.Record(Foo{I: j}))
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, r.Close())
+	assert.Len(t, readLines(t, path), 50)
+}
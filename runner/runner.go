@@ -0,0 +1,403 @@
+// Package runner fuzzes a function with typed values in a loop, in a plain
+// binary rather than under `go test -fuzz`, for long campaigns in environments
+// without the Go toolchain.
+//
+// Values are mutated structurally by a fuzzing.Mutator, starting from a corpus
+// of JSON files. The runner has no coverage feedback: the Go toolchain only
+// exposes coverage to the engine of `go test -fuzz`, so the runner cannot tell
+// which mutated values are interesting, and explores blindly around the
+// corpus. Seed it well. Crashes, panics and errors returned by the function,
+// are saved next to the corpus. Crashes that kill the process, such as fatal
+// errors and panics in other goroutines, are not caught.
+package runner
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hugoklepsch/go-fuzz-all/fuzzing"
+)
+
+// Option configures a Runner.
+type Option func(*options)
+
+type options struct {
+	dir              string
+	seed             uint64
+	workers          int
+	maxIterations    int64
+	progress         io.Writer
+	progressInterval time.Duration
+	mutators         []fuzzing.MutatorOption
+}
+
+// WithCorpusDir reads the corpus from the JSON files in dir, such as
+// testdata/fuzz-all/FuzzMyFunc, writes values passed to Add there, and saves
+// crashes to its crashers subdirectory. Without a corpus directory, crashes
+// are only kept in memory.
+func WithCorpusDir(dir string) Option {
+	return func(o *options) {
+		o.dir = dir
+	}
+}
+
+// WithSeed seeds the random choices of the runner, to repeat a run. The
+// default is a random seed.
+func WithSeed(seed uint64) Option {
+	return func(o *options) {
+		o.seed = seed
+	}
+}
+
+// WithWorkers runs the function from n goroutines, which requires it to be
+// safe for concurrent use. The default is 1.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithMaxIterations stops the run after the function was called n times. The
+// default is to run until the context is done.
+func WithMaxIterations(n int64) Option {
+	return func(o *options) {
+		o.maxIterations = n
+	}
+}
+
+// WithProgress writes the number of iterations, crashes and corpus entries to
+// w, every interval.
+func WithProgress(w io.Writer, interval time.Duration) Option {
+	return func(o *options) {
+		o.progress = w
+		o.progressInterval = interval
+	}
+}
+
+// WithMutators registers user-defined mutators, created with
+// fuzzing.WithTypeMutator and fuzzing.WithFieldMutator, with the Mutator of
+// every worker.
+func WithMutators(mutators ...fuzzing.MutatorOption) Option {
+	return func(o *options) {
+		o.mutators = append(o.mutators, mutators...)
+	}
+}
+
+// PanicError is the error of a Crash caused by a panic.
+type PanicError struct {
+	// Value is the recovered value.
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Crash is an input for which the function returned an error or panicked.
+type Crash[T any] struct {
+	Input T
+	// Err is the returned error, or a *PanicError.
+	Err error
+	// Path is the file the input was saved to, if there is a corpus
+	// directory. It is a .json file, or a .txt file with the input as a Go
+	// literal if the input has no JSON encoding, such as a NaN float.
+	Path string
+	// SaveErr is the error saving the input to the corpus directory, if that
+	// failed.
+	SaveErr error
+}
+
+// Stats summarizes a run.
+type Stats struct {
+	Iterations int64
+	Crashes    int
+	Corpus     int
+	Elapsed    time.Duration
+}
+
+// Runner calls a function with mutated values of T. It is safe for concurrent
+// use.
+type Runner[T any] struct {
+	fn   func(T) error
+	opts options
+
+	mu      sync.Mutex
+	corpus  []T
+	crashes []Crash[T]
+	// crashed holds the messages of the crashes, which are only saved once.
+	crashed map[string]bool
+}
+
+// New returns a Runner for fn, and reads the corpus directory if there is one.
+// It returns an error if a mutator passed to WithMutators can never apply to
+// values of T.
+func New[T any](fn func(T) error, opts ...Option) (*Runner[T], error) {
+	o := options{
+		seed:    rand.Uint64(),
+		workers: 1,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// Validate the mutators before running.
+	if err := fuzzing.CheckMutators[T](o.mutators...); err != nil {
+		return nil, fmt.Errorf("WithMutators: %w", err)
+	}
+	r := &Runner[T]{
+		fn:      fn,
+		opts:    o,
+		crashed: map[string]bool{},
+	}
+	if o.dir != "" {
+		corpus, err := readCorpus[T](o.dir)
+		if err != nil {
+			return nil, err
+		}
+		r.corpus = corpus
+	}
+	return r, nil
+}
+
+// Add adds v to the corpus, and writes it to the corpus directory if there is
+// one.
+func (r *Runner[T]) Add(v T) error {
+	if r.opts.dir != "" {
+		if _, err := writeJSON(r.opts.dir, v); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.corpus = append(r.corpus, v)
+	return nil
+}
+
+// Crashes returns the crashes found so far, one per error message.
+func (r *Runner[T]) Crashes() []Crash[T] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Crash[T]{}, r.crashes...)
+}
+
+// Run calls the function with mutated values until ctx is done or the maximum
+// number of iterations is reached. A crash that could not be saved is kept in
+// Crashes, with its SaveErr, and the first such error is returned after the
+// run.
+func (r *Runner[T]) Run(ctx context.Context) (Stats, error) {
+	start := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	iterations := atomic.Int64{}
+	var errOnce sync.Once
+	var saveErr error
+
+	wg := sync.WaitGroup{}
+	if r.opts.progress != nil && r.opts.progressInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.reportProgress(ctx, start, &iterations)
+		}()
+	}
+	workers := sync.WaitGroup{}
+	for worker := 0; worker < max(r.opts.workers, 1); worker++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			random := rand.New(rand.NewPCG(r.opts.seed, uint64(worker)))
+			mutator := fuzzing.NewMutator[T](random, r.opts.mutators...)
+			for ctx.Err() == nil {
+				n := iterations.Add(1)
+				if r.opts.maxIterations > 0 && n > r.opts.maxIterations {
+					iterations.Add(-1)
+					return
+				}
+				if err := r.iterate(random, mutator); err != nil {
+					errOnce.Do(func() {
+						saveErr = err
+					})
+				}
+			}
+		}()
+	}
+	workers.Wait()
+	cancel()
+	wg.Wait()
+	return r.stats(start, iterations.Load()), saveErr
+}
+
+func (r *Runner[T]) stats(start time.Time, iterations int64) Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Stats{
+		Iterations: iterations,
+		Crashes:    len(r.crashes),
+		Corpus:     len(r.corpus),
+		Elapsed:    time.Since(start),
+	}
+}
+
+func (r *Runner[T]) reportProgress(ctx context.Context, start time.Time, iterations *atomic.Int64) {
+	ticker := time.NewTicker(r.opts.progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := r.stats(start, iterations.Load())
+			fmt.Fprintf(r.opts.progress, "runner: elapsed %v, %d iterations (%.0f/s), %d crashes, corpus %d\n",
+				stats.Elapsed.Round(time.Second), stats.Iterations, float64(stats.Iterations)/stats.Elapsed.Seconds(),
+				stats.Crashes, stats.Corpus)
+		}
+	}
+}
+
+// iterate calls the function with a value mutated from a random corpus entry.
+func (r *Runner[T]) iterate(random *rand.Rand, mutator *fuzzing.Mutator[T]) error {
+	var input T
+	r.mu.Lock()
+	if len(r.corpus) > 0 {
+		input = r.corpus[random.IntN(len(r.corpus))]
+		if len(r.corpus) > 1 && random.IntN(4) == 0 {
+			input = mutator.Splice(input, r.corpus[random.IntN(len(r.corpus))])
+		}
+	}
+	r.mu.Unlock()
+	for i := random.IntN(4); i >= 0; i-- {
+		input = mutator.Mutate(input)
+	}
+	if err := r.call(input); err != nil {
+		return r.crash(input, err)
+	}
+	return nil
+}
+
+func (r *Runner[T]) call(input T) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = &PanicError{Value: recovered, Stack: debug.Stack()}
+		}
+	}()
+	return r.fn(input)
+}
+
+// crash records the crash of input with err, unless a crash with the same
+// message was recorded before, and returns the error saving it, if any. The
+// crash is recorded even if it could not be saved.
+func (r *Runner[T]) crash(input T, err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.crashed[err.Error()] {
+		return nil
+	}
+	r.crashed[err.Error()] = true
+	crash := Crash[T]{Input: input, Err: err}
+	if r.opts.dir != "" {
+		crash.Path, crash.SaveErr = saveCrash(filepath.Join(r.opts.dir, "crashers"), input, err)
+	}
+	r.crashes = append(r.crashes, crash)
+	return crash.SaveErr
+}
+
+// saveCrash writes input into dir as JSON, next to a .txt file with err and
+// input as a Go literal. If input has no JSON encoding, only the .txt file is
+// written, as the Go literal is lossless.
+func saveCrash[T any](dir string, input T, err error) (string, error) {
+	data, jsonErr := encodeJSON(input)
+	report := &strings.Builder{}
+	fmt.Fprintf(report, "%v\n", err)
+	if panicErr, ok := err.(*PanicError); ok {
+		fmt.Fprintf(report, "\n%s", panicErr.Stack)
+	}
+	literal := fuzzing.GoLiteral(input)
+	fmt.Fprintf(report, "\ninput as Go literal:\n\t%s\n", literal)
+	if fuzzing.UsesPtrHelper(literal) {
+		fmt.Fprintf(report, "\t%s\n", fuzzing.PtrHelper)
+	}
+	if jsonErr != nil {
+		fmt.Fprintf(report, "\nnot saved as JSON: %v\n", jsonErr)
+		return writeHashed(dir, []byte(report.String()), ".txt")
+	}
+	path, writeErr := writeHashed(dir, data, ".json")
+	if writeErr != nil {
+		return "", writeErr
+	}
+	reportPath := strings.TrimSuffix(path, ".json") + ".txt"
+	if err := os.WriteFile(reportPath, []byte(report.String()), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// writeJSON writes v into dir as a JSON file named after the hash of its
+// content, and returns its path.
+func writeJSON(dir string, v any) (string, error) {
+	data, err := encodeJSON(v)
+	if err != nil {
+		return "", err
+	}
+	return writeHashed(dir, data, ".json")
+}
+
+func encodeJSON(v any) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// writeHashed writes data into dir as a file named after the hash of data,
+// with extension ext, and returns its path.
+func writeHashed(dir string, data []byte, ext string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%x", sha256.Sum256(data))[:16]+ext)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// readCorpus reads the JSON files in dir, in a stable order. A missing
+// directory is an empty corpus.
+func readCorpus[T any](dir string) ([]T, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	corpus := []T{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var v T
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		// Catch typos in field names, rather than silently ignoring the field.
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&v); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		corpus = append(corpus, v)
+	}
+	return corpus, nil
+}
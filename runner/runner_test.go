@@ -0,0 +1,217 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/hugoklepsch/go-fuzz-all/fuzzing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type Foo struct {
+	S     string
+	Items []int
+	P     *int
+}
+
+func TestRunner_Run(t *testing.T) {
+	dir := t.TempDir()
+	r, err := New(func(foo Foo) error {
+		if len(foo.Items) > 2 {
+			panic("too many items")
+		}
+		if foo.P != nil && *foo.P < 0 {
+			return errors.New("negative")
+		}
+		return nil
+	}, WithCorpusDir(dir), WithSeed(1), WithMaxIterations(20000))
+	require.NoError(t, err)
+	require.NoError(t, r.Add(Foo{S: "foo", Items: []int{1}}))
+
+	stats, err := r.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(20000), stats.Iterations)
+	assert.Equal(t, 1, stats.Corpus)
+	assert.Equal(t, 2, stats.Crashes)
+
+	crashes := r.Crashes()
+	require.Len(t, crashes, 2)
+	messages := []string{}
+	for _, crash := range crashes {
+		messages = append(messages, crash.Err.Error())
+		assert.Equal(t, filepath.Join(dir, "crashers"), filepath.Dir(crash.Path))
+		report, err := os.ReadFile(strings.TrimSuffix(crash.Path, ".json") + ".txt")
+		require.NoError(t, err)
+		assert.Contains(t, string(report), "input as Go literal:\n\tFoo{")
+	}
+	assert.ElementsMatch(t, []string{"panic: too many items", "negative"}, messages)
+	for _, crash := range crashes {
+		var panicErr *PanicError
+		if errors.As(crash.Err, &panicErr) {
+			assert.Greater(t, len(crash.Input.Items), 2)
+			assert.Contains(t, string(panicErr.Stack), "runner_test.go")
+		}
+	}
+
+	// The corpus is read back, and crashers are not part of it.
+	r, err = New(func(foo Foo) error { return nil }, WithCorpusDir(dir))
+	require.NoError(t, err)
+	stats, err = r.Run(ctxDone())
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Corpus)
+}
+
+func TestRunner_RunUntilDone(t *testing.T) {
+	calls := atomic.Int64{}
+	r, err := New(func(foo Foo) error {
+		calls.Add(1)
+		return nil
+	}, WithWorkers(4))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	stats, err := r.Run(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, stats.Iterations, int64(0))
+	assert.Equal(t, calls.Load(), stats.Iterations)
+	assert.Empty(t, r.Crashes())
+}
+
+func TestRunner_Progress(t *testing.T) {
+	progress := &syncBuffer{}
+	r, err := New(func(foo Foo) error { return nil }, WithProgress(progress, 10*time.Millisecond))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = r.Run(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, progress.String(), "iterations")
+}
+
+func TestRunner_WithMutators(t *testing.T) {
+	r, err := New(func(foo Foo) error {
+		if foo.S == "magic" {
+			return errors.New("magic")
+		}
+		return nil
+	}, WithSeed(1), WithMaxIterations(100), WithMutators(
+		fuzzing.WithFieldMutator("S", func(s *string, r *rand.Rand) {
+			*s = "magic"
+		}),
+	))
+	require.NoError(t, err)
+	_, err = r.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, r.Crashes(), 1)
+	assert.Equal(t, "magic", r.Crashes()[0].Input.S)
+
+	_, err = New(func(foo Foo) error { return nil }, WithMutators(
+		fuzzing.WithFieldMutator("T", func(s *string, r *rand.Rand) {}),
+	))
+	assert.EqualError(t, err, `WithMutators: no value of type string at field path "T" in runner.Foo`)
+}
+
+func TestRunner_RunNaN(t *testing.T) {
+	dir := t.TempDir()
+	r, err := New(func(in struct{ F float64 }) error {
+		if in.F != in.F {
+			return errors.New("boom")
+		}
+		return nil
+	}, WithCorpusDir(dir), WithSeed(1), WithMaxIterations(2000))
+	require.NoError(t, err)
+	stats, err := r.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2000), stats.Iterations)
+	assert.Equal(t, 1, stats.Crashes)
+
+	// NaN has no JSON encoding, so the input is only saved as a Go literal.
+	crash := r.Crashes()[0]
+	assert.NoError(t, crash.SaveErr)
+	assert.Equal(t, ".txt", filepath.Ext(crash.Path))
+	report, err := os.ReadFile(crash.Path)
+	require.NoError(t, err)
+	assert.Contains(t, string(report), "input as Go literal:\n\tstruct { F float64 }{F: math.NaN()}")
+	assert.Contains(t, string(report), "not saved as JSON: json: unsupported value: NaN")
+}
+
+func TestRunner_RunSaveFails(t *testing.T) {
+	dir := t.TempDir()
+	// The crashers directory cannot be created.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "crashers"), nil, 0o644))
+	r, err := New(func(foo Foo) error {
+		if foo.S != "" {
+			return errors.New("not empty")
+		}
+		return nil
+	}, WithCorpusDir(dir), WithSeed(1), WithMaxIterations(1000))
+	require.NoError(t, err)
+	stats, err := r.Run(context.Background())
+	assert.ErrorContains(t, err, "crashers")
+	// The run goes on, and the crash is kept in memory.
+	assert.Equal(t, int64(1000), stats.Iterations)
+	require.Len(t, r.Crashes(), 1)
+	assert.Equal(t, err, r.Crashes()[0].SaveErr)
+	assert.Empty(t, r.Crashes()[0].Path)
+}
+
+func TestSaveCrash_PtrHelper(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		input  Foo
+		helper bool
+	}{
+		{Foo{P: ptr(1)}, true},
+		{Foo{S: "ptr(1)"}, false},
+	} {
+		path, err := saveCrash(dir, tc.input, errors.New("boom"))
+		require.NoError(t, err)
+		report, err := os.ReadFile(strings.TrimSuffix(path, ".json") + ".txt")
+		require.NoError(t, err)
+		assert.Equal(t, tc.helper, strings.Contains(string(report), fuzzing.PtrHelper), string(report))
+	}
+}
+
+func TestNew_BadCorpus(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"Typo": 1}`), 0o644))
+	_, err := New(func(foo Foo) error { return nil }, WithCorpusDir(dir))
+	assert.ErrorContains(t, err, "bad.json")
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func ctxDone() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}